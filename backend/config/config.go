@@ -0,0 +1,566 @@
+// Package config 在启动时一次性加载应用配置。
+//
+// 配置来源按优先级从高到低为：进程环境变量、.env.local、.env、配置文件。
+// 配置文件由 CONFIG_FILE 指定，未指定时依次查找 config.yaml、config.yml、config.toml。
+// 文件中的键与环境变量同名，嵌套结构用下划线连接，例如 github: {client_id: ...} 等价于 GITHUB_CLIENT_ID。
+// 按路由名称配置的限流（RATE_LIMIT_<NAME>）在加载时收集到 RateLimitConfig.Rates 中。
+package config
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Config 应用配置
+type Config struct {
+	// Env 运行环境：development 或 production（可简写为 prod），production 时启用严格校验
+	Env  string
+	Port string
+	// GinMode gin 的运行模式：debug、release 或 test，生产环境默认 release
+	GinMode   string
+	Database  DatabaseConfig
+	Auth      AuthConfig
+	Admin     AdminConfig
+	OAuth     OAuthConfig
+	CORS      CORSConfig
+	Site      SiteConfig
+	Storage   StorageConfig
+	Comments  CommentConfig
+	Views     ViewConfig
+	RateLimit RateLimitConfig
+	Scheduler SchedulerConfig
+	// TrustedProxies 允许通过 X-Forwarded-For 等请求头传递客户端 IP 的反向代理地址或网段，
+	// 默认为空，即直接使用连接的对端地址，避免客户端伪造 IP 绕过限流
+	TrustedProxies []string
+}
+
+// 支持的数据库驱动
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+	DriverMySQL    = "mysql"
+)
+
+type DatabaseConfig struct {
+	// Driver 数据库驱动：sqlite、postgres 或 mysql
+	Driver string
+	// DSN 连接串，SQLite 未设置时使用 Name 作为文件路径
+	DSN string
+	// Name SQLite 数据库文件路径
+	Name string
+
+	// 连接池设置，0 表示使用驱动默认值
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+type AuthConfig struct {
+	JWTSecret       string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	// InviteTTL 后台用户邀请码的有效期
+	InviteTTL time.Duration
+	Login     LoginThrottleConfig
+}
+
+// LoginThrottleConfig 登录失败限制：按用户名和来源 IP 分别计数，在 FailureWindow 内达到上限后锁定 Lockout
+type LoginThrottleConfig struct {
+	MaxFailures   int
+	IPMaxFailures int
+	FailureWindow time.Duration
+	Lockout       time.Duration
+}
+
+// AdminConfig 启动时确保存在的站点所有者账号，两项都为空时跳过
+type AdminConfig struct {
+	Username string
+	Password string
+}
+
+// OAuthProviderConfig 单个第三方登录提供方的凭据，ClientID 为空表示未启用
+type OAuthProviderConfig struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+}
+
+func (p OAuthProviderConfig) Enabled() bool {
+	return p.ClientID != ""
+}
+
+type OAuthConfig struct {
+	GitHub           OAuthProviderConfig
+	GitLab           OAuthProviderConfig
+	GitLabURL        string
+	Google           OAuthProviderConfig
+	OIDC             OAuthProviderConfig
+	OIDCIssuerURL    string
+	OIDCProviderName string
+	// RedirectAllowlist 登录完成后允许跳转的额外地址，FRONTEND_URL 总是允许
+	RedirectAllowlist []string
+}
+
+type CORSConfig struct {
+	AllowOrigins []string
+}
+
+type SiteConfig struct {
+	// URL 订阅源等对外链接使用的站点地址，未设置时使用 FrontendURL
+	URL         string
+	FrontendURL string
+	Title       string
+	// BackendURL 后端 API 的对外地址，订阅源自身、关联登录等指向后端的绝对链接都由它生成，
+	// 不使用请求中可被客户端伪造的 Host 和 X-Forwarded-* 请求头。开发环境默认 http://localhost:<PORT>
+	BackendURL string
+}
+
+// StorageConfig 上传文件的存储后端
+type StorageConfig struct {
+	// Driver 存储驱动，目前只支持 local
+	Driver string
+	// Dir 本地存储的根目录
+	Dir string
+	// URLPrefix 上传文件对外访问的路径前缀
+	URLPrefix string
+	// MaxUploadSize 单个文件的大小上限（字节）
+	MaxUploadSize int64
+}
+
+type CommentConfig struct {
+	// Moderation 新评论是否需要审核
+	Moderation bool
+	// TrustedBypass 已有评论通过审核的访客是否无需再次审核
+	TrustedBypass bool
+	// EditWindow 访客可以修改或删除自己评论的时间窗口
+	EditWindow time.Duration
+}
+
+// ViewConfig 文章浏览量统计
+type ViewConfig struct {
+	// DedupWindow 同一读者在该时间内重复访问同一篇文章只计一次
+	DedupWindow time.Duration
+	// FlushInterval 内存中的浏览量写入数据库的间隔
+	FlushInterval time.Duration
+	// DedupMaxEntries 内存中最多保留的去重记录数，超出时淘汰最早的记录
+	DedupMaxEntries int
+}
+
+type RateLimitConfig struct {
+	Enabled bool
+	// Rates 按路由名称（小写）覆盖默认限流，值的格式如 "5/1m" 或 "off"
+	Rates map[string]string
+}
+
+type SchedulerConfig struct {
+	// PostInterval 检查并发布到期定时文章的间隔
+	PostInterval time.Duration
+}
+
+const (
+	EnvDevelopment = "development"
+	EnvProduction  = "production"
+
+	minJWTSecretLength     = 32
+	minAdminPasswordLength = 8
+)
+
+// 示例配置中常见的占位密钥，生产环境禁止使用
+var insecureJWTSecrets = []string{
+	"your_super_secret_key_change_this",
+	"fallback_secret_key_for_dev_only_change_me",
+	"secret",
+	"changeme",
+}
+
+// Default 返回默认配置，未加载配置时各模块使用它
+func Default() *Config {
+	return &Config{
+		Env:  EnvDevelopment,
+		Port: "8080",
+		Database: DatabaseConfig{
+			Driver: DriverSQLite,
+			Name:   "gin_blog.db",
+		},
+		Auth: AuthConfig{
+			AccessTokenTTL:  15 * time.Minute,
+			RefreshTokenTTL: 30 * 24 * time.Hour,
+			InviteTTL:       7 * 24 * time.Hour,
+			Login: LoginThrottleConfig{
+				MaxFailures:   5,
+				IPMaxFailures: 20,
+				FailureWindow: 15 * time.Minute,
+				Lockout:       15 * time.Minute,
+			},
+		},
+		OAuth: OAuthConfig{
+			OIDCProviderName: "oidc",
+		},
+		CORS: CORSConfig{
+			AllowOrigins: []string{"http://localhost:3000"},
+		},
+		Site: SiteConfig{
+			URL:         "http://localhost:3000",
+			FrontendURL: "http://localhost:3000",
+			Title:       "Gin Blog",
+		},
+		Storage: StorageConfig{
+			Driver:        "local",
+			Dir:           "uploads",
+			URLPrefix:     "/uploads",
+			MaxUploadSize: 10 << 20,
+		},
+		Comments: CommentConfig{
+			Moderation:    true,
+			TrustedBypass: true,
+			EditWindow:    15 * time.Minute,
+		},
+		Views: ViewConfig{
+			DedupWindow:     30 * time.Minute,
+			FlushInterval:   10 * time.Second,
+			DedupMaxEntries: 100000,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Rates:   map[string]string{},
+		},
+		Scheduler: SchedulerConfig{
+			PostInterval: time.Minute,
+		},
+	}
+}
+
+// IsProduction 是否运行在生产环境
+func (c *Config) IsProduction() bool {
+	return c.Env == EnvProduction
+}
+
+// Load 加载 .env 文件和配置文件，解析并校验配置。生产环境下存在不安全的配置时返回错误。
+func Load() (*Config, error) {
+	loadDotenv(".env.local", ".env")
+	if err := loadConfigFile(); err != nil {
+		return nil, err
+	}
+
+	cfg, err := fromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadDotenv 依次加载 .env 文件，已存在的环境变量不会被覆盖，因此排在前面的文件优先
+func loadDotenv(files ...string) {
+	for _, file := range files {
+		if _, err := os.Stat(file); err != nil {
+			continue
+		}
+		if err := godotenv.Load(file); err != nil {
+			log.Printf("Warning: failed to load %s: %v", file, err)
+			continue
+		}
+		log.Printf("Info: %s file loaded successfully.", file)
+	}
+}
+
+func fromEnv() (*Config, error) {
+	cfg := Default()
+	p := &envParser{}
+
+	p.string("APP_ENV", &cfg.Env)
+	cfg.Env = strings.ToLower(cfg.Env)
+	switch cfg.Env {
+	case EnvDevelopment, EnvProduction:
+	case "prod":
+		cfg.Env = EnvProduction
+	default:
+		// 拼错的环境名不能悄悄退回开发模式，否则会跳过生产环境的校验
+		p.errs = append(p.errs, fmt.Sprintf("APP_ENV: unsupported environment %q, expected development or production", cfg.Env))
+	}
+	p.string("PORT", &cfg.Port)
+	p.string("GIN_MODE", &cfg.GinMode)
+	cfg.GinMode = strings.ToLower(cfg.GinMode)
+	switch cfg.GinMode {
+	case "":
+		if cfg.Env == EnvProduction {
+			cfg.GinMode = "release"
+		}
+	case "debug", "release", "test":
+	default:
+		p.errs = append(p.errs, fmt.Sprintf("GIN_MODE: unsupported mode %q, expected debug, release or test", cfg.GinMode))
+	}
+	p.list("TRUSTED_PROXIES", &cfg.TrustedProxies)
+	p.string("DB_DRIVER", &cfg.Database.Driver)
+	p.string("DB_DSN", &cfg.Database.DSN)
+	p.string("DB_NAME", &cfg.Database.Name)
+	p.int("DB_MAX_OPEN_CONNS", &cfg.Database.MaxOpenConns)
+	p.int("DB_MAX_IDLE_CONNS", &cfg.Database.MaxIdleConns)
+	p.duration("DB_CONN_MAX_LIFETIME", &cfg.Database.ConnMaxLifetime)
+	p.duration("DB_CONN_MAX_IDLE_TIME", &cfg.Database.ConnMaxIdleTime)
+	cfg.Database.Driver = strings.ToLower(cfg.Database.Driver)
+	switch cfg.Database.Driver {
+	case DriverSQLite:
+	case "postgresql", "pg":
+		cfg.Database.Driver = DriverPostgres
+		fallthrough
+	case DriverPostgres, DriverMySQL:
+		if cfg.Database.DSN == "" {
+			p.errs = append(p.errs, fmt.Sprintf("DB_DSN is required for the %s driver", cfg.Database.Driver))
+		}
+	default:
+		p.errs = append(p.errs, fmt.Sprintf("DB_DRIVER: unsupported driver %q, expected sqlite, postgres or mysql", cfg.Database.Driver))
+	}
+
+	p.string("JWT_SECRET", &cfg.Auth.JWTSecret)
+	p.duration("ACCESS_TOKEN_TTL", &cfg.Auth.AccessTokenTTL)
+	p.duration("REFRESH_TOKEN_TTL", &cfg.Auth.RefreshTokenTTL)
+	p.duration("USER_INVITE_TTL", &cfg.Auth.InviteTTL)
+	p.positiveInt("LOGIN_MAX_FAILURES", &cfg.Auth.Login.MaxFailures)
+	p.positiveInt("LOGIN_IP_MAX_FAILURES", &cfg.Auth.Login.IPMaxFailures)
+	p.duration("LOGIN_FAILURE_WINDOW", &cfg.Auth.Login.FailureWindow)
+	p.duration("LOGIN_LOCKOUT_DURATION", &cfg.Auth.Login.Lockout)
+
+	p.string("ADMIN_USERNAME", &cfg.Admin.Username)
+	p.string("ADMIN_PASSWORD", &cfg.Admin.Password)
+
+	p.provider("GITHUB", &cfg.OAuth.GitHub)
+	p.provider("GITLAB", &cfg.OAuth.GitLab)
+	p.string("GITLAB_URL", &cfg.OAuth.GitLabURL)
+	p.provider("GOOGLE", &cfg.OAuth.Google)
+	p.provider("OIDC", &cfg.OAuth.OIDC)
+	p.string("OIDC_ISSUER_URL", &cfg.OAuth.OIDCIssuerURL)
+	p.string("OIDC_PROVIDER_NAME", &cfg.OAuth.OIDCProviderName)
+	p.list("OAUTH_REDIRECT_ALLOWLIST", &cfg.OAuth.RedirectAllowlist)
+
+	if p.string("FRONTEND_URL", &cfg.Site.FrontendURL) {
+		cfg.Site.FrontendURL = strings.TrimRight(cfg.Site.FrontendURL, "/")
+		// 未单独配置时，站点地址和跨域来源都跟随前端地址
+		cfg.Site.URL = cfg.Site.FrontendURL
+		cfg.CORS.AllowOrigins = []string{cfg.Site.FrontendURL}
+	}
+	if p.string("SITE_URL", &cfg.Site.URL) {
+		cfg.Site.URL = strings.TrimRight(cfg.Site.URL, "/")
+	}
+	p.string("SITE_TITLE", &cfg.Site.Title)
+	if p.string("BACKEND_URL", &cfg.Site.BackendURL) {
+		cfg.Site.BackendURL = strings.TrimRight(cfg.Site.BackendURL, "/")
+	}
+	p.list("CORS_ALLOW_ORIGINS", &cfg.CORS.AllowOrigins)
+
+	p.string("STORAGE_DRIVER", &cfg.Storage.Driver)
+	cfg.Storage.Driver = strings.ToLower(cfg.Storage.Driver)
+	if cfg.Storage.Driver != "local" {
+		p.errs = append(p.errs, fmt.Sprintf("STORAGE_DRIVER: unsupported driver %q, expected local", cfg.Storage.Driver))
+	}
+	p.string("UPLOAD_DIR", &cfg.Storage.Dir)
+	p.string("UPLOAD_URL_PREFIX", &cfg.Storage.URLPrefix)
+	maxUploadSize := int(cfg.Storage.MaxUploadSize)
+	p.positiveInt("UPLOAD_MAX_SIZE", &maxUploadSize)
+	cfg.Storage.MaxUploadSize = int64(maxUploadSize)
+
+	p.bool("COMMENT_MODERATION", &cfg.Comments.Moderation)
+	p.bool("COMMENT_TRUSTED_BYPASS", &cfg.Comments.TrustedBypass)
+	p.duration("COMMENT_EDIT_WINDOW", &cfg.Comments.EditWindow)
+
+	p.duration("VIEW_DEDUP_WINDOW", &cfg.Views.DedupWindow)
+	p.duration("VIEW_FLUSH_INTERVAL", &cfg.Views.FlushInterval)
+	p.positiveInt("VIEW_DEDUP_MAX_ENTRIES", &cfg.Views.DedupMaxEntries)
+
+	p.bool("RATE_LIMIT_ENABLED", &cfg.RateLimit.Enabled)
+	p.prefixed("RATE_LIMIT_", cfg.RateLimit.Rates)
+	delete(cfg.RateLimit.Rates, "enabled")
+
+	p.duration("POST_SCHEDULER_INTERVAL", &cfg.Scheduler.PostInterval)
+
+	if len(p.errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration:\n  %s", strings.Join(p.errs, "\n  "))
+	}
+	return cfg, nil
+}
+
+// validate 检查配置是否完整和安全。生产环境下问题会导致启动失败，开发环境只给出警告。
+func (c *Config) validate() error {
+	var problems []string
+
+	secret := c.Auth.JWTSecret
+	switch {
+	case secret == "":
+		problems = append(problems, "JWT_SECRET is not set")
+	case len(secret) < minJWTSecretLength:
+		problems = append(problems, fmt.Sprintf("JWT_SECRET must be at least %d characters long", minJWTSecretLength))
+	case containsFold(insecureJWTSecrets, secret):
+		problems = append(problems, "JWT_SECRET is using a well-known placeholder value")
+	}
+
+	if c.Admin.Password != "" && len(c.Admin.Password) < minAdminPasswordLength {
+		problems = append(problems, fmt.Sprintf("ADMIN_PASSWORD must be at least %d characters long", minAdminPasswordLength))
+	}
+	if (c.Admin.Username == "") != (c.Admin.Password == "") {
+		problems = append(problems, "ADMIN_USERNAME and ADMIN_PASSWORD must be set together")
+	}
+	if containsFold(c.CORS.AllowOrigins, "*") {
+		problems = append(problems, "CORS_ALLOW_ORIGINS must not contain the wildcard origin *")
+	}
+	for name, provider := range map[string]OAuthProviderConfig{
+		"GITHUB": c.OAuth.GitHub, "GITLAB": c.OAuth.GitLab, "GOOGLE": c.OAuth.Google, "OIDC": c.OAuth.OIDC,
+	} {
+		if provider.Enabled() && (provider.ClientSecret == "" || provider.RedirectURL == "") {
+			problems = append(problems, fmt.Sprintf("%s_CLIENT_SECRET and %s_REDIRECT_URL are required when %s_CLIENT_ID is set", name, name, name))
+		}
+	}
+
+	if c.Site.BackendURL == "" {
+		problems = append(problems, "BACKEND_URL is not set")
+	}
+
+	if len(problems) > 0 && c.IsProduction() {
+		return fmt.Errorf("insecure configuration for production:\n  %s", strings.Join(problems, "\n  "))
+	}
+	for _, problem := range problems {
+		log.Printf("Warning: %s", problem)
+	}
+
+	if secret == "" {
+		// 开发环境生成临时密钥，重启后已签发的 token 全部失效
+		c.Auth.JWTSecret = randomSecret()
+		log.Println("Warning: using a random JWT secret for this process, tokens will not survive a restart.")
+	}
+	if c.Site.BackendURL == "" {
+		c.Site.BackendURL = "http://localhost:" + c.Port
+	}
+	return nil
+}
+
+func randomSecret() string {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		log.Fatalf("Failed to generate JWT secret: %v", err)
+	}
+	return hex.EncodeToString(buf)
+}
+
+func containsFold(values []string, target string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// envParser 读取环境变量并收集解析错误，便于一次报告全部问题
+type envParser struct {
+	errs []string
+}
+
+// string 读取非空的环境变量，返回是否已设置
+func (p *envParser) string(name string, dst *string) bool {
+	value := strings.TrimSpace(os.Getenv(name))
+	if value == "" {
+		return false
+	}
+	*dst = value
+	return true
+}
+
+func (p *envParser) duration(name string, dst *time.Duration) {
+	var value string
+	if !p.string(name, &value) {
+		return
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		p.errs = append(p.errs, fmt.Sprintf("%s: invalid duration %q", name, value))
+		return
+	}
+	*dst = parsed
+}
+
+// int 读取非负整数
+func (p *envParser) int(name string, dst *int) {
+	var value string
+	if !p.string(name, &value) {
+		return
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 0 {
+		p.errs = append(p.errs, fmt.Sprintf("%s: invalid number %q", name, value))
+		return
+	}
+	*dst = parsed
+}
+
+// positiveInt 读取正整数
+func (p *envParser) positiveInt(name string, dst *int) {
+	var value string
+	if !p.string(name, &value) {
+		return
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed <= 0 {
+		p.errs = append(p.errs, fmt.Sprintf("%s: invalid positive number %q", name, value))
+		return
+	}
+	*dst = parsed
+}
+
+func (p *envParser) bool(name string, dst *bool) {
+	var value string
+	if !p.string(name, &value) {
+		return
+	}
+	switch strings.ToLower(value) {
+	case "1", "true", "yes", "on":
+		*dst = true
+	case "0", "false", "no", "off":
+		*dst = false
+	default:
+		p.errs = append(p.errs, fmt.Sprintf("%s: invalid boolean %q", name, value))
+	}
+}
+
+// prefixed 收集以 prefix 开头的全部环境变量，键为去掉前缀后的小写名称
+func (p *envParser) prefixed(prefix string, dst map[string]string) {
+	for _, entry := range os.Environ() {
+		name, value, _ := strings.Cut(entry, "=")
+		if !strings.HasPrefix(name, prefix) || name == prefix {
+			continue
+		}
+		if value = strings.TrimSpace(value); value != "" {
+			dst[strings.ToLower(strings.TrimPrefix(name, prefix))] = value
+		}
+	}
+}
+
+// list 读取逗号分隔的列表，忽略空项
+func (p *envParser) list(name string, dst *[]string) {
+	var value string
+	if !p.string(name, &value) {
+		return
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*dst = items
+}
+
+func (p *envParser) provider(prefix string, dst *OAuthProviderConfig) {
+	p.string(prefix+"_CLIENT_ID", &dst.ClientID)
+	p.string(prefix+"_CLIENT_SECRET", &dst.ClientSecret)
+	p.string(prefix+"_REDIRECT_URL", &dst.RedirectURL)
+}
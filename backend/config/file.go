@@ -0,0 +1,99 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+var defaultConfigFiles = []string{"config.yaml", "config.yml", "config.toml"}
+
+// loadConfigFile 读取 YAML/TOML 配置文件，把其中的值写入尚未设置的环境变量
+func loadConfigFile() error {
+	path := os.Getenv("CONFIG_FILE")
+	if path == "" {
+		for _, candidate := range defaultConfigFiles {
+			if _, err := os.Stat(candidate); err == nil {
+				path = candidate
+				break
+			}
+		}
+		if path == "" {
+			return nil
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file %s: %v", path, err)
+	}
+
+	values := map[string]interface{}{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return fmt.Errorf("unsupported config file format %s, expected .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse config file %s: %v", path, err)
+	}
+
+	flat := map[string]string{}
+	if err := flatten("", values, flat); err != nil {
+		return fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+	keys := make([]string, 0, len(flat))
+	for key := range flat {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if _, exists := os.LookupEnv(key); exists {
+			continue
+		}
+		if err := os.Setenv(key, flat[key]); err != nil {
+			return fmt.Errorf("failed to apply %s from config file: %v", key, err)
+		}
+	}
+	log.Printf("Info: config file %s loaded successfully.", path)
+	return nil
+}
+
+// flatten 将嵌套结构展开为环境变量形式的键，例如 github.client_id -> GITHUB_CLIENT_ID，列表用逗号连接
+func flatten(prefix string, value interface{}, out map[string]string) error {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			name := strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+			if prefix != "" {
+				name = prefix + "_" + name
+			}
+			if err := flatten(name, child, out); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			switch item.(type) {
+			case map[string]interface{}, []interface{}:
+				return fmt.Errorf("%s: lists may only contain plain values", prefix)
+			}
+			items = append(items, fmt.Sprint(item))
+		}
+		out[prefix] = strings.Join(items, ",")
+	case nil:
+	default:
+		out[prefix] = fmt.Sprint(v)
+	}
+	return nil
+}
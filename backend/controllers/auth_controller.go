@@ -2,16 +2,24 @@ package controllers
 
 import (
 	"net/http"
-	"os"
 
 	"gin-blog/backend/database"
 	"gin-blog/backend/models"
-	"gin-blog/backend/utils"
 
 	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+// dummyPasswordHash 是一个随机密码的 bcrypt 哈希，cost 与 HashPassword 相同。
+// 用户不存在或尚未接受邀请时也用它校验一次密码，使响应时间与密码错误时一致，不泄露账号是否存在、是否已激活。
+const dummyPasswordHash = "$2a$14$0.imLQoEamVzJH626Dn.leJtcenWOJruEkg4E0Ep06xi5woa3o26C"
+
+// compareDummyPassword 执行一次结果必然失败的密码校验，只用于消除时间差
+func compareDummyPassword(password string) {
+	bcrypt.CompareHashAndPassword([]byte(dummyPasswordHash), []byte(password))
+}
+
 type LoginInput struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
@@ -24,9 +32,16 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	// 先检查失败次数限制，被锁定的用户名或 IP 不再查询用户和校验密码
+	if !checkLoginThrottle(c, input.Username) {
+		return
+	}
+
 	var user models.User
 	if err := database.DB.Where("username = ?", input.Username).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
+			compareDummyPassword(input.Password)
+			recordLoginFailure(c, input.Username)
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
 			return
 		}
@@ -34,22 +49,37 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	adminUsernameFromEnv := os.Getenv("ADMIN_USERNAME")
-	if adminUsernameFromEnv != "" && user.Username != adminUsernameFromEnv {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login restricted to admin user only"})
+	// 尚未接受邀请的账号没有密码，按密码错误处理，避免泄露账号状态
+	if user.IsPending() {
+		compareDummyPassword(input.Password)
+		recordLoginFailure(c, input.Username)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
 		return
 	}
 
 	if err := user.CheckPassword(input.Password); err != nil {
+		recordLoginFailure(c, input.Username)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
 		return
 	}
 
-	token, err := utils.GenerateToken(user.ID, user.Username, "", "admin")
+	if user.Disabled {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account has been disabled"})
+		return
+	}
+
+	if user.TOTPEnabled {
+		respondLoginChallenge(c, &user)
+		return
+	}
+
+	subject := adminSubject(&user)
+	tokens, _, err := issueSession(database.DB, c, subject, "")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
+	clearLoginFailures(user.Username)
 
-	c.JSON(http.StatusOK, gin.H{"token": token, "user_id": user.ID, "username": user.Username, "type": "admin"})
+	c.JSON(http.StatusOK, sessionResponse(subject, tokens))
 }
\ No newline at end of file
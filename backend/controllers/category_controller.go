@@ -4,6 +4,7 @@ import (
 	"gin-blog/backend/database"
 	"gin-blog/backend/models"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -11,7 +12,10 @@ import (
 )
 
 type CategoryInput struct {
-	Name string `json:"name" binding:"required"`
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	SortOrder   int    `json:"sort_order"`
+	ParentID    *uint  `json:"parent_id,omitempty"`
 }
 
 func CreateCategory(c *gin.Context) {
@@ -38,7 +42,26 @@ func CreateCategory(c *gin.Context) {
 		return
 	}
 
-	category := models.Category{Name: trimmedName}
+	if input.ParentID != nil {
+		if err := database.DB.First(&models.Category{}, *input.ParentID).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parent category not found"})
+			return
+		}
+	}
+
+	slug, err := uniqueSlug(database.DB, &models.Category{}, models.SlugKindCategory, trimmedName, 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate category slug: " + err.Error()})
+		return
+	}
+
+	category := models.Category{
+		Name:        trimmedName,
+		Slug:        slug,
+		Description: strings.TrimSpace(input.Description),
+		SortOrder:   input.SortOrder,
+		ParentID:    input.ParentID,
+	}
 	if err := database.DB.Create(&category).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create category: " + err.Error()})
 		return
@@ -46,11 +69,287 @@ func CreateCategory(c *gin.Context) {
 	c.JSON(http.StatusCreated, category)
 }
 
+// categoryPostCounts 统计每个分类下当前请求可见的文章数
+func categoryPostCounts(c *gin.Context) (map[uint]int64, error) {
+	db := database.DB.Model(&models.Post{}).Where("category_id IS NOT NULL")
+	if scope := visiblePostScope(c); scope != nil {
+		db = scope(db)
+	}
+
+	var rows []struct {
+		CategoryID uint
+		Count      int64
+	}
+	if err := db.Select("category_id, COUNT(*) AS count").Group("category_id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	counts := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		counts[row.CategoryID] = row.Count
+	}
+	return counts, nil
+}
+
+// buildCategoryTree 按 parent_id 组装分类树，父分类不存在的分类作为根节点
+func buildCategoryTree(categories []models.Category) []*models.Category {
+	nodes := make(map[uint]*models.Category, len(categories))
+	for i := range categories {
+		nodes[categories[i].ID] = &categories[i]
+	}
+
+	roots := []*models.Category{}
+	for i := range categories {
+		node := &categories[i]
+		if node.ParentID != nil {
+			if parent, ok := nodes[*node.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+	return roots
+}
+
+// GetCategories 返回所有分类及其文章数，?tree=true 时返回树形结构
 func GetCategories(c *gin.Context) {
 	var categories []models.Category
-	if err := database.DB.Order("name asc").Find(&categories).Error; err != nil {
+	if err := database.DB.Order("sort_order asc, name asc").Find(&categories).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve categories"})
 		return
 	}
+
+	counts, err := categoryPostCounts(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count category posts"})
+		return
+	}
+	for i := range categories {
+		categories[i].PostCount = counts[categories[i].ID]
+	}
+
+	if tree, _ := strconv.ParseBool(c.Query("tree")); tree {
+		c.JSON(http.StatusOK, buildCategoryTree(categories))
+		return
+	}
 	c.JSON(http.StatusOK, categories)
-}
\ No newline at end of file
+}
+
+// findCategory 按 ID 或 slug 查找分类，失败时直接写入错误响应
+func findCategory(c *gin.Context) (*models.Category, bool) {
+	var category models.Category
+	id, _, err := resolveSlugID(&models.Category{}, models.SlugKindCategory, c.Param("id"))
+	if err == nil {
+		err = database.DB.First(&category, id).Error
+	}
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve category"})
+		return nil, false
+	}
+	return &category, true
+}
+
+// GetCategory 按 ID 或 slug 获取单个分类
+func GetCategory(c *gin.Context) {
+	category, ok := findCategory(c)
+	if !ok {
+		return
+	}
+	counts, err := categoryPostCounts(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count category posts"})
+		return
+	}
+	category.PostCount = counts[category.ID]
+	c.JSON(http.StatusOK, category)
+}
+
+type UpdateCategoryInput struct {
+	Name        *string `json:"name,omitempty"`
+	Slug        *string `json:"slug,omitempty"`
+	Description *string `json:"description,omitempty"`
+	SortOrder   *int    `json:"sort_order,omitempty"`
+	ParentID    *uint   `json:"parent_id,omitempty"`
+	SetParent   *bool   `json:"set_parent,omitempty"` // 为 true 时按 parent_id 更新父分类，parent_id 为空表示移到顶层
+}
+
+// checkCategoryParent 校验新的父分类存在，且不是分类自身或其子孙，避免形成环
+func checkCategoryParent(tx *gorm.DB, categoryID, parentID uint) (string, error) {
+	for current := parentID; ; {
+		if current == categoryID {
+			return "A category cannot be moved under itself or its descendants", nil
+		}
+		var parent models.Category
+		if err := tx.Select("id", "parent_id").First(&parent, current).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return "Parent category not found", nil
+			}
+			return "", err
+		}
+		if parent.ParentID == nil {
+			return "", nil
+		}
+		current = *parent.ParentID
+	}
+}
+
+// UpdateCategory 修改分类名称、描述、排序和父分类，改名后旧 slug 保留跳转
+func UpdateCategory(c *gin.Context) {
+	category, ok := findCategory(c)
+	if !ok {
+		return
+	}
+
+	var input UpdateCategoryInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	slugSource := ""
+	if input.Name != nil {
+		name := strings.TrimSpace(*input.Name)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Category name cannot be empty"})
+			return
+		}
+		if name != category.Name {
+			var count int64
+			if err := database.DB.Model(&models.Category{}).Where("name = ? AND id <> ?", name, category.ID).Count(&count).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error checking category: " + err.Error()})
+				return
+			}
+			if count > 0 {
+				c.JSON(http.StatusConflict, gin.H{"error": "Category with this name already exists"})
+				return
+			}
+			updates["name"] = name
+			slugSource = name
+		}
+	}
+	if input.Slug != nil && strings.TrimSpace(*input.Slug) != "" {
+		slugSource = *input.Slug
+	}
+	if input.Description != nil {
+		updates["description"] = strings.TrimSpace(*input.Description)
+	}
+	if input.SortOrder != nil {
+		updates["sort_order"] = *input.SortOrder
+	}
+	if input.SetParent != nil && *input.SetParent {
+		if input.ParentID != nil {
+			msg, err := checkCategoryParent(database.DB, category.ID, *input.ParentID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check parent category"})
+				return
+			}
+			if msg != "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+				return
+			}
+		}
+		updates["parent_id"] = input.ParentID
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if slugSource != "" {
+			slug, err := uniqueSlug(tx, &models.Category{}, models.SlugKindCategory, slugSource, category.ID)
+			if err != nil {
+				return err
+			}
+			if slug != category.Slug {
+				if err := recordSlugRedirect(tx, models.SlugKindCategory, category.Slug, slug, category.ID); err != nil {
+					return err
+				}
+				updates["slug"] = slug
+			}
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+		return tx.Model(category).Updates(updates).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update category: " + err.Error()})
+		return
+	}
+
+	var updated models.Category
+	if err := database.DB.First(&updated, category.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve updated category"})
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteCategory 删除分类。默认与外键的 OnDelete:SET NULL 一致，将其文章置为未分类；
+// 指定 ?reassign_to=<分类ID> 时将文章移到目标分类。子分类会上移到被删除分类的父分类下。
+func DeleteCategory(c *gin.Context) {
+	category, ok := findCategory(c)
+	if !ok {
+		return
+	}
+
+	var target *uint
+	if value := c.Query("reassign_to"); value != "" {
+		targetID, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reassign_to category ID"})
+			return
+		}
+		if uint(targetID) == category.ID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot reassign posts to the category being deleted"})
+			return
+		}
+		if err := database.DB.First(&models.Category{}, uint(targetID)).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Target category not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve target category"})
+			return
+		}
+		id := uint(targetID)
+		target = &id
+	}
+
+	var moved int64
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// posts_moved 只统计未删除的文章
+		if err := tx.Model(&models.Post{}).Where("category_id = ?", category.ID).Count(&moved).Error; err != nil {
+			return err
+		}
+		// 软删除不会触发外键约束，因此需要显式更新文章（包括已删除的文章）
+		if err := tx.Unscoped().Model(&models.Post{}).Where("category_id = ?", category.ID).Update("category_id", target).Error; err != nil {
+			return err
+		}
+		// 历史版本同样指向被删除的分类，恢复旧版本时不能得到一个不存在的分类
+		if err := tx.Unscoped().Model(&models.PostRevision{}).Where("category_id = ?", category.ID).Update("category_id", target).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Unscoped().Model(&models.Category{}).Where("parent_id = ?", category.ID).Update("parent_id", category.ParentID).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("kind = ? AND target_id = ?", models.SlugKindCategory, category.ID).Delete(&models.SlugRedirect{}).Error; err != nil {
+			return err
+		}
+		// 物理删除，使名称和 slug 可以被新分类重新使用
+		return tx.Unscoped().Delete(category).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete category: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Category deleted",
+		"posts_moved":   moved,
+		"reassigned_to": target,
+	})
+}
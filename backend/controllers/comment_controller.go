@@ -3,21 +3,36 @@ package controllers
 import (
 	"gin-blog/backend/database"
 	"gin-blog/backend/models"
+	"math"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultCommentMaxDepth = 5
+	maxCommentMaxDepth     = 20
+	defaultCommentPageSize = 20
+	maxCommentPageSize     = 100
 )
 
 type CreateCommentInput struct {
-	Content string `json:"content" binding:"required"`
+	Content  string `json:"content" binding:"required"`
+	ParentID *uint  `json:"parent_id,omitempty"`
+}
+
+type CommentListQuery struct {
+	MaxDepth int `form:"max_depth"`
+	Page     int `form:"page"`
+	PageSize int `form:"page_size"`
 }
 
 func CreateComment(c *gin.Context) {
-	postIDStr := c.Param("id")
-	postID, err := strconv.ParseUint(postIDStr, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID format"})
+	postID, ok := resolvePostParam(c)
+	if !ok {
 		return
 	}
 
@@ -34,15 +49,35 @@ func CreateComment(c *gin.Context) {
 	}
 
 	var post models.Post
-	if err := database.DB.First(&post, uint(postID)).Error; err != nil {
+	if err := database.DB.First(&post, uint(postID)).Error; err != nil || !post.IsPublic(time.Now()) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
 		return
 	}
 
+	if input.ParentID != nil {
+		var parent models.Comment
+		if err := visibleCommentScope(c)(database.DB).First(&parent, *input.ParentID).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parent comment not found"})
+			return
+		}
+		if parent.PostID != uint(postID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parent comment belongs to a different post"})
+			return
+		}
+	}
+
+	status, err := initialCommentStatus(guestUserID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to determine comment status"})
+		return
+	}
+
 	comment := models.Comment{
 		Content:     input.Content,
 		PostID:      uint(postID),
 		GuestUserID: guestUserID.(uint),
+		ParentID:    input.ParentID,
+		Status:      status,
 	}
 
 	if err := database.DB.Create(&comment).Error; err != nil {
@@ -54,19 +89,232 @@ func CreateComment(c *gin.Context) {
 	c.JSON(http.StatusCreated, comment)
 }
 
+// GetCommentsForPost 以树形结构返回文章评论，分页作用于顶层评论。
+// 超过 max_depth 的回复会被提升到最大深度，与其父评论并列显示，避免嵌套过深。
 func GetCommentsForPost(c *gin.Context) {
-	postIDStr := c.Param("id")
-	postID, err := strconv.ParseUint(postIDStr, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID format"})
+	postID, ok := resolvePostParam(c)
+	if !ok {
 		return
 	}
 
-	var comments []models.Comment
-	if err := database.DB.Preload("GuestUser").Where("post_id = ?", uint(postID)).Order("created_at asc").Find(&comments).Error; err != nil {
+	var q CommentListQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters: " + err.Error()})
+		return
+	}
+	if q.MaxDepth <= 0 {
+		q.MaxDepth = defaultCommentMaxDepth
+	}
+	if q.MaxDepth > maxCommentMaxDepth {
+		q.MaxDepth = maxCommentMaxDepth
+	}
+	if q.Page <= 0 {
+		q.Page = 1
+	}
+	if q.PageSize <= 0 {
+		q.PageSize = defaultCommentPageSize
+	}
+	if q.PageSize > maxCommentPageSize {
+		q.PageSize = maxCommentPageSize
+	}
+
+	// 已删除的评论只要还有回复就需要以占位形式保留，因此这里不使用默认的软删除过滤
+	scope := visibleCommentScope(c)
+	topLevel := scope(database.DB.Unscoped().Model(&models.Comment{})).
+		Where("post_id = ? AND parent_id IS NULL", uint(postID)).
+		Where("(comments.deleted_at IS NULL OR EXISTS (SELECT 1 FROM comments AS r WHERE r.parent_id = comments.id AND r.deleted_at IS NULL))")
+
+	var totalThreads int64
+	if err := topLevel.Session(&gorm.Session{}).Count(&totalThreads).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve comments"})
 		return
 	}
 
-	c.JSON(http.StatusOK, comments)
-}
\ No newline at end of file
+	var roots []*models.Comment
+	if err := topLevel.Session(&gorm.Session{}).Preload("GuestUser").Order("created_at asc").
+		Offset((q.Page - 1) * q.PageSize).Limit(q.PageSize).Find(&roots).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve comments"})
+		return
+	}
+
+	var replies []*models.Comment
+	if err := scope(database.DB.Unscoped()).Preload("GuestUser").Where("post_id = ? AND parent_id IS NOT NULL", uint(postID)).
+		Order("created_at asc, id asc").Find(&replies).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve comments"})
+		return
+	}
+
+	var totalComments int64
+	if err := scope(database.DB.Model(&models.Comment{})).Where("post_id = ?", uint(postID)).Count(&totalComments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve comments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":           pruneDeletedComments(buildCommentTree(roots, replies, q.MaxDepth)),
+		"total":          totalThreads,
+		"total_comments": totalComments,
+		"page":           q.Page,
+		"page_size":      q.PageSize,
+		"total_pages":    int(math.Ceil(float64(totalThreads) / float64(q.PageSize))),
+	})
+}
+
+// pruneDeletedComments 移除没有回复的已删除评论，仍有回复的已删除评论替换为占位内容
+func pruneDeletedComments(comments []*models.Comment) []*models.Comment {
+	kept := make([]*models.Comment, 0, len(comments))
+	for _, comment := range comments {
+		comment.Replies = pruneDeletedComments(comment.Replies)
+		if comment.DeletedAt.Valid {
+			if len(comment.Replies) == 0 {
+				continue
+			}
+			comment.Deleted = true
+			comment.Content = ""
+			comment.GuestUserID = 0
+			comment.GuestUser = models.GuestUser{}
+		}
+		kept = append(kept, comment)
+	}
+	return kept
+}
+
+// buildCommentTree 将回复挂载到当前页的顶层评论下，不属于当前页的回复会被忽略
+func buildCommentTree(roots, replies []*models.Comment, maxDepth int) []*models.Comment {
+	byID := make(map[uint]*models.Comment, len(roots)+len(replies))
+	depth := make(map[uint]int, len(roots)+len(replies))
+	for _, root := range roots {
+		root.Replies = []*models.Comment{}
+		byID[root.ID] = root
+		depth[root.ID] = 0
+	}
+	for _, reply := range replies {
+		reply.Replies = []*models.Comment{}
+		byID[reply.ID] = reply
+	}
+
+	// 回复按创建时间升序排列，父评论总是先于子评论出现，因此一次遍历即可确定深度
+	for _, reply := range replies {
+		parent, ok := byID[*reply.ParentID]
+		if !ok {
+			continue
+		}
+		parentDepth, ok := depth[parent.ID]
+		if !ok {
+			continue
+		}
+		for parentDepth >= maxDepth && parent.ParentID != nil {
+			parent = byID[*parent.ParentID]
+			parentDepth--
+		}
+		parent.Replies = append(parent.Replies, reply)
+		depth[reply.ID] = parentDepth + 1
+	}
+
+	if roots == nil {
+		return []*models.Comment{}
+	}
+	return roots
+}
+
+type UpdateCommentInput struct {
+	Content string `json:"content" binding:"required"`
+}
+
+// findPostComment 查找属于指定文章的评论，文章可以用 ID 或 slug 指定
+func findPostComment(c *gin.Context) (*models.Comment, bool) {
+	postID, ok := resolvePostParam(c)
+	if !ok {
+		return nil, false
+	}
+	commentID, err := strconv.ParseUint(c.Param("commentId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID format"})
+		return nil, false
+	}
+
+	var comment models.Comment
+	if err := database.DB.Where("post_id = ?", postID).First(&comment, uint(commentID)).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve comment"})
+		return nil, false
+	}
+	return &comment, true
+}
+
+// checkCommentOwnership 校验访客是否为评论作者且仍在可编辑时间窗口内
+func checkCommentOwnership(c *gin.Context, comment *models.Comment) bool {
+	guestUserID := readerGuestUserID(c)
+	if guestUserID == nil || comment.GuestUserID != *guestUserID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only modify your own comments"})
+		return false
+	}
+	if time.Since(comment.CreatedAt) > appConfig.Comments.EditWindow {
+		c.JSON(http.StatusForbidden, gin.H{"error": "The time window for modifying this comment has expired"})
+		return false
+	}
+	return true
+}
+
+// UpdateComment 访客在时间窗口内修改自己的评论
+func UpdateComment(c *gin.Context) {
+	comment, ok := findPostComment(c)
+	if !ok {
+		return
+	}
+	if !checkCommentOwnership(c, comment) {
+		return
+	}
+	if comment.Status == models.CommentStatusRejected || comment.Status == models.CommentStatusSpam {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Rejected comments cannot be edited"})
+		return
+	}
+
+	var input UpdateCommentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 修改后的内容需要重新走一遍审核规则
+	status, err := initialCommentStatus(comment.GuestUserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to determine comment status"})
+		return
+	}
+
+	now := time.Now()
+	if err := database.DB.Model(comment).Updates(map[string]interface{}{
+		"content":   input.Content,
+		"status":    status,
+		"edited_at": now,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update comment"})
+		return
+	}
+
+	database.DB.Preload("GuestUser").First(comment, comment.ID)
+	c.JSON(http.StatusOK, comment)
+}
+
+// DeleteComment 访客在时间窗口内删除自己的评论，有审核权限的后台用户可以删除任意评论。
+// 评论为软删除，仍有回复时在列表中显示为占位内容。
+func DeleteComment(c *gin.Context) {
+	comment, ok := findPostComment(c)
+	if !ok {
+		return
+	}
+	canModerate := isAdminRequest(c) && currentUser(c).Can(models.PermModerateComments)
+	if !canModerate && !checkCommentOwnership(c, comment) {
+		return
+	}
+
+	if err := database.DB.Delete(comment).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete comment"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted"})
+}
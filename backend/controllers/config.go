@@ -0,0 +1,11 @@
+package controllers
+
+import "gin-blog/backend/config"
+
+// appConfig 启动时加载的应用配置，未调用 Configure 时使用默认值
+var appConfig = config.Default()
+
+// Configure 注入应用配置，需在注册路由之前调用
+func Configure(cfg *config.Config) {
+	appConfig = cfg
+}
@@ -0,0 +1,396 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"gin-blog/backend/database"
+	"gin-blog/backend/models"
+	"gin-blog/backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const feedItemLimit = 20
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Language      string    `xml:"language,omitempty"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	AtomLink      rssLink   `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Author      string   `xml:"author,omitempty"`
+	Categories  []string `xml:"category"`
+	Description cdata    `xml:"description"`
+}
+
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+type cdata struct {
+	Value string `xml:",cdata"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Link       atomLink       `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Author     *atomAuthor    `xml:"author,omitempty"`
+	Categories []atomCategory `xml:"category"`
+	Content    atomContent    `xml:"content"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// feedSource 描述一个订阅源及其包含的文章
+type feedSource struct {
+	title       string
+	description string
+	selfPath    string
+	posts       []models.Post
+	updated     time.Time
+}
+
+func siteURL() string {
+	return appConfig.Site.URL
+}
+
+func siteTitle() string {
+	return appConfig.Site.Title
+}
+
+func postURL(post *models.Post) string {
+	if post.Slug != "" {
+		return fmt.Sprintf("%s/post/%s", siteURL(), url.PathEscape(post.Slug))
+	}
+	return fmt.Sprintf("%s/post/%d", siteURL(), post.ID)
+}
+
+// backendURL 返回配置的后端对外地址，用于生成订阅源自身等指向 API 的链接
+func backendURL() string {
+	return appConfig.Site.BackendURL
+}
+
+func postPublishedTime(post *models.Post) time.Time {
+	if post.PublishedAt != nil {
+		return *post.PublishedAt
+	}
+	if post.PublishAt != nil {
+		return *post.PublishAt
+	}
+	return post.CreatedAt
+}
+
+// loadFeedPosts 按与文章列表相同的筛选逻辑获取最新的公开文章
+func loadFeedPosts(q PostListQuery) ([]models.Post, error) {
+	db, err := applyPostFilters(publicPostScope(database.DB.Model(&models.Post{})), &q)
+	if err != nil {
+		return nil, err
+	}
+	var posts []models.Post
+	err = db.Preload("User").Preload("Tags").Preload("Category").
+		Order("posts.created_at desc").Limit(feedItemLimit).Find(&posts).Error
+	return posts, err
+}
+
+func newFeedSource(title, description, selfPath string, posts []models.Post) *feedSource {
+	source := &feedSource{title: title, description: description, selfPath: selfPath, posts: posts}
+	for i := range posts {
+		if posts[i].UpdatedAt.After(source.updated) {
+			source.updated = posts[i].UpdatedAt
+		}
+	}
+	if source.updated.IsZero() {
+		source.updated = time.Unix(0, 0).UTC()
+	}
+	return source
+}
+
+// etag 根据订阅源地址和文章的更新时间计算
+func (f *feedSource) etag(format string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s|%s", format, f.selfPath)
+	for i := range f.posts {
+		fmt.Fprintf(h, "|%d:%d", f.posts[i].ID, f.posts[i].UpdatedAt.UnixNano())
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil))[:32] + `"`
+}
+
+// notModified 处理 If-None-Match / If-Modified-Since 条件请求，命中时直接返回 304
+func notModified(c *gin.Context, etag string, lastModified time.Time) bool {
+	c.Header("ETag", etag)
+	c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	c.Header("Cache-Control", "public, max-age=300")
+
+	if match := c.GetHeader("If-None-Match"); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == etag || candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+				c.Status(http.StatusNotModified)
+				return true
+			}
+		}
+		return false
+	}
+
+	if since := c.GetHeader("If-Modified-Since"); since != "" {
+		if t, err := http.ParseTime(since); err == nil && !lastModified.Truncate(time.Second).After(t) {
+			c.Status(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+func renderPostHTML(post *models.Post) string {
+	if post.ContentHTML != "" {
+		return post.ContentHTML
+	}
+	html, err := utils.RenderMarkdown(post.Content)
+	if err != nil {
+		return ""
+	}
+	return html
+}
+
+func postTagNames(post *models.Post) []string {
+	names := make([]string, 0, len(post.Tags)+1)
+	if post.Category != nil {
+		names = append(names, post.Category.Name)
+	}
+	for _, tag := range post.Tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+func writeRSS(c *gin.Context, source *feedSource) {
+	etag := source.etag("rss")
+	if notModified(c, etag, source.updated) {
+		return
+	}
+
+	channel := rssChannel{
+		Title:         source.title,
+		Link:          siteURL(),
+		Description:   source.description,
+		LastBuildDate: source.updated.Format(time.RFC1123Z),
+		AtomLink:      rssLink{Href: backendURL() + source.selfPath, Rel: "self", Type: "application/rss+xml"},
+		Items:         make([]rssItem, 0, len(source.posts)),
+	}
+	for i := range source.posts {
+		post := &source.posts[i]
+		link := postURL(post)
+		channel.Items = append(channel.Items, rssItem{
+			Title:       post.Title,
+			Link:        link,
+			GUID:        rssGUID{Value: link, IsPermaLink: true},
+			PubDate:     postPublishedTime(post).Format(time.RFC1123Z),
+			Categories:  postTagNames(post),
+			Description: cdata{Value: renderPostHTML(post)},
+		})
+	}
+
+	writeXML(c, "application/rss+xml; charset=utf-8", rssFeed{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		Channel: channel,
+	})
+}
+
+func writeAtom(c *gin.Context, source *feedSource) {
+	etag := source.etag("atom")
+	if notModified(c, etag, source.updated) {
+		return
+	}
+
+	self := backendURL() + source.selfPath
+	feed := atomFeed{
+		Title:   source.title,
+		ID:      self,
+		Updated: source.updated.Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: self, Rel: "self", Type: "application/atom+xml"},
+			{Href: siteURL(), Rel: "alternate", Type: "text/html"},
+		},
+		Entries: make([]atomEntry, 0, len(source.posts)),
+	}
+	for i := range source.posts {
+		post := &source.posts[i]
+		link := postURL(post)
+		entry := atomEntry{
+			Title:     post.Title,
+			ID:        link,
+			Link:      atomLink{Href: link, Rel: "alternate", Type: "text/html"},
+			Published: postPublishedTime(post).Format(time.RFC3339),
+			Updated:   post.UpdatedAt.Format(time.RFC3339),
+			Content:   atomContent{Type: "html", Value: renderPostHTML(post)},
+		}
+		if post.User.Username != "" {
+			entry.Author = &atomAuthor{Name: post.User.Username}
+		}
+		for _, name := range postTagNames(post) {
+			entry.Categories = append(entry.Categories, atomCategory{Term: name})
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	writeXML(c, "application/atom+xml; charset=utf-8", feed)
+}
+
+func writeXML(c *gin.Context, contentType string, v interface{}) {
+	out, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成订阅源失败"})
+		return
+	}
+	c.Data(http.StatusOK, contentType, append([]byte(xml.Header), out...))
+}
+
+// siteFeedSource 全站最新文章
+func siteFeedSource(c *gin.Context, selfPath string) (*feedSource, bool) {
+	posts, err := loadFeedPosts(PostListQuery{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取订阅源文章失败"})
+		return nil, false
+	}
+	return newFeedSource(siteTitle(), siteTitle()+" 最新文章", selfPath, posts), true
+}
+
+// tagFeedSource 与 GetPostsByTag 使用相同的标签查找逻辑
+func tagFeedSource(c *gin.Context, selfPath string) (*feedSource, bool) {
+	tag, ok := findTagByName(c, c.Param("tagName"))
+	if !ok {
+		return nil, false
+	}
+	posts, err := loadFeedPosts(PostListQuery{Tags: []string{tag.Name}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取订阅源文章失败"})
+		return nil, false
+	}
+	return newFeedSource(fmt.Sprintf("%s - 标签: %s", siteTitle(), tag.Name), "标签 "+tag.Name+" 下的最新文章", selfPath, posts), true
+}
+
+func categoryFeedSource(c *gin.Context, selfPath string) (*feedSource, bool) {
+	var category models.Category
+	categoryID, _, err := resolveSlugID(&models.Category{}, models.SlugKindCategory, c.Param("categoryId"))
+	if err == nil {
+		err = database.DB.First(&category, categoryID).Error
+	}
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "分类未找到"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查找分类失败"})
+		return nil, false
+	}
+	id := category.ID
+	posts, err := loadFeedPosts(PostListQuery{CategoryID: &id})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取订阅源文章失败"})
+		return nil, false
+	}
+	return newFeedSource(fmt.Sprintf("%s - 分类: %s", siteTitle(), category.Name), "分类 "+category.Name+" 下的最新文章", selfPath, posts), true
+}
+
+// GetRSSFeed 全站 RSS 2.0 订阅源
+func GetRSSFeed(c *gin.Context) {
+	if source, ok := siteFeedSource(c, c.Request.URL.Path); ok {
+		writeRSS(c, source)
+	}
+}
+
+// GetAtomFeed 全站 Atom 订阅源
+func GetAtomFeed(c *gin.Context) {
+	if source, ok := siteFeedSource(c, c.Request.URL.Path); ok {
+		writeAtom(c, source)
+	}
+}
+
+// GetTagRSSFeed 标签 RSS 2.0 订阅源
+func GetTagRSSFeed(c *gin.Context) {
+	if source, ok := tagFeedSource(c, c.Request.URL.Path); ok {
+		writeRSS(c, source)
+	}
+}
+
+// GetTagAtomFeed 标签 Atom 订阅源
+func GetTagAtomFeed(c *gin.Context) {
+	if source, ok := tagFeedSource(c, c.Request.URL.Path); ok {
+		writeAtom(c, source)
+	}
+}
+
+// GetCategoryRSSFeed 分类 RSS 2.0 订阅源
+func GetCategoryRSSFeed(c *gin.Context) {
+	if source, ok := categoryFeedSource(c, c.Request.URL.Path); ok {
+		writeRSS(c, source)
+	}
+}
+
+// GetCategoryAtomFeed 分类 Atom 订阅源
+func GetCategoryAtomFeed(c *gin.Context) {
+	if source, ok := categoryFeedSource(c, c.Request.URL.Path); ok {
+		writeAtom(c, source)
+	}
+}
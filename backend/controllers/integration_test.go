@@ -0,0 +1,638 @@
+package controllers_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gin-blog/backend/config"
+	"gin-blog/backend/controllers"
+	"gin-blog/backend/database"
+	"gin-blog/backend/migrations"
+	"gin-blog/backend/models"
+	"gin-blog/backend/routes"
+	"gin-blog/backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 集成测试默认使用临时 SQLite 文件。设置 TEST_DB_DRIVER（postgres 或 mysql）和 TEST_DB_DSN 后
+// 改为连接该数据库，每个测试开始前回滚全部迁移再重新执行，因此应使用专门的测试库。
+
+// testDatabaseConfig 根据环境变量返回测试数据库配置
+func testDatabaseConfig(t *testing.T) config.DatabaseConfig {
+	t.Helper()
+	driver := os.Getenv("TEST_DB_DRIVER")
+	if driver == "" || driver == config.DriverSQLite {
+		return config.DatabaseConfig{Driver: config.DriverSQLite, Name: filepath.Join(t.TempDir(), "test.db")}
+	}
+	dsn := os.Getenv("TEST_DB_DSN")
+	if dsn == "" {
+		t.Fatalf("TEST_DB_DSN is required when TEST_DB_DRIVER is %q", driver)
+	}
+	return config.DatabaseConfig{Driver: driver, DSN: dsn}
+}
+
+// resetSchema 回滚全部迁移后重新执行，得到空的最新表结构
+func resetSchema(t *testing.T) {
+	t.Helper()
+	if _, err := migrations.Down(database.DB, len(migrations.All())); err != nil {
+		t.Fatalf("roll back migrations: %v", err)
+	}
+	if _, err := migrations.Up(database.DB); err != nil {
+		t.Fatalf("apply migrations: %v", err)
+	}
+}
+
+// setupTestServer 连接测试数据库、执行迁移并启动完整路由，configure 可在启动前调整配置
+func setupTestServer(t *testing.T, configure func(cfg *config.Config)) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg := config.Default()
+	cfg.Auth.JWTSecret = "controllers-test-secret-0123456789"
+	cfg.Database = testDatabaseConfig(t)
+	cfg.RateLimit.Enabled = false
+	if configure != nil {
+		configure(cfg)
+	}
+
+	utils.ConfigureJWT(cfg.Auth.JWTSecret, cfg.Auth.AccessTokenTTL)
+	controllers.Configure(cfg)
+	database.ConnectDatabase(cfg.Database)
+	t.Cleanup(func() {
+		if sqlDB, err := database.DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	resetSchema(t)
+	// 与启动时一样检测全文索引，使用 -tags sqlite_fts5 时检索走 FTS5
+	database.PrepareSchema(false)
+	utils.TokenRevocationCheck = controllers.CheckTokenRevocation
+
+	r := gin.New()
+	routes.SetupRouter(r, cfg)
+	return r
+}
+
+// createPublishedPost 以指定作者写入一篇已发布的文章
+func createPublishedPost(t *testing.T, author *models.User, title string) *models.Post {
+	t.Helper()
+	now := time.Now()
+	post := &models.Post{
+		Title:       title,
+		Slug:        fmt.Sprintf("post-%d", now.UnixNano()),
+		Content:     "body",
+		UserID:      author.ID,
+		Status:      models.PostStatusPublished,
+		PublishedAt: &now,
+	}
+	if err := database.DB.Create(post).Error; err != nil {
+		t.Fatalf("create post %q: %v", title, err)
+	}
+	return post
+}
+
+func createUser(t *testing.T, username, password string) *models.User {
+	t.Helper()
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &models.User{Username: username, Password: hash, Role: models.RoleAuthor}
+	if err := database.DB.Create(user).Error; err != nil {
+		t.Fatalf("create user %q: %v", username, err)
+	}
+	return user
+}
+
+// serve 以指定 User-Agent 发送请求，不同的 User-Agent 被视为不同的匿名读者
+func serve(r *gin.Engine, method, target, userAgent string, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("User-Agent", userAgent)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestMigrationsRoundTrip(t *testing.T) {
+	setupTestServer(t, nil)
+	migrator := database.DB.Migrator()
+
+	rolledBack, err := migrations.Down(database.DB, len(migrations.All()))
+	if err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+	if rolledBack != len(migrations.All()) {
+		t.Errorf("rolled back %d migrations, want %d", rolledBack, len(migrations.All()))
+	}
+	for _, model := range []interface{}{&models.Post{}, &models.GuestIdentity{}, &models.RefreshToken{}} {
+		if migrator.HasTable(model) {
+			t.Errorf("table for %T still exists after rolling back", model)
+		}
+	}
+	pending, err := migrations.Pending(database.DB)
+	if err != nil || len(pending) != len(migrations.All()) {
+		t.Fatalf("pending = %d, %v; want %d", len(pending), err, len(migrations.All()))
+	}
+
+	applied, err := migrations.Up(database.DB)
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if applied != len(migrations.All()) {
+		t.Errorf("applied %d migrations, want %d", applied, len(migrations.All()))
+	}
+	if !migrator.HasColumn(&models.RefreshToken{}, "Provider") || !migrator.HasColumn(&models.OAuthLoginCode{}, "Provider") {
+		t.Error("provider columns missing after migrating up")
+	}
+	if applied, err := migrations.Up(database.DB); err != nil || applied != 0 {
+		t.Errorf("second Up applied %d, %v; want 0", applied, err)
+	}
+}
+
+func TestSearchEscapesLikePatterns(t *testing.T) {
+	r := setupTestServer(t, nil)
+	author := createUser(t, "author", "password123")
+	for _, title := range []string{
+		"100% Coverage", "100 percent coverage",
+		"snake_case names", "snakeXcase names",
+		`C:\temp paths`, "C:temp paths",
+	} {
+		createPublishedPost(t, author, title)
+	}
+	// 转小写会改变字节长度的非 ASCII 标题和正文
+	unicodePost := createPublishedPost(t, author, "İstanbul Ⱥ 指南")
+	if err := database.DB.Model(unicodePost).Update("content", strings.Repeat("Ⱥ", 100)+" needle").Error; err != nil {
+		t.Fatal(err)
+	}
+	// 文章直接写入数据库，启用 FTS5 时需要手动写入全文索引
+	var posts []models.Post
+	database.DB.Find(&posts)
+	for i := range posts {
+		if err := database.IndexPost(database.DB, &posts[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"go_lang", "goXlang"} {
+		if err := database.DB.Create(&models.Tag{Name: name, Slug: strings.ToLower(name)}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		query  string
+		titles []string
+		tags   []string
+	}{
+		{query: "0%", titles: []string{"100% Coverage"}},
+		{query: "e_c", titles: []string{"snake_case names"}},
+		{query: `:\t`, titles: []string{`C:\temp paths`}},
+		// PostgreSQL 使用 ILIKE，各数据库均不区分大小写
+		{query: "COVERAGE", titles: []string{"100 percent coverage", "100% Coverage"}},
+		{query: "o_l", tags: []string{"go_lang"}},
+		{query: "needle", titles: []string{"İstanbul Ⱥ 指南"}},
+		{query: "指南", titles: []string{"İstanbul Ⱥ 指南"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			w := serve(r, http.MethodGet, "/api/search?q="+url.QueryEscape(tt.query), "search-test", "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+			}
+			var resp struct {
+				Results []struct {
+					Post models.Post `json:"post"`
+				} `json:"results"`
+				Tags []models.Tag `json:"tags"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+
+			titles := []string{}
+			for _, result := range resp.Results {
+				titles = append(titles, result.Post.Title)
+			}
+			tags := []string{}
+			for _, tag := range resp.Tags {
+				tags = append(tags, tag.Name)
+			}
+			if want := strings.Join(tt.titles, "|"); !sameItems(titles, tt.titles) {
+				t.Errorf("titles = %q, want %q", strings.Join(titles, "|"), want)
+			}
+			if want := strings.Join(tt.tags, "|"); !sameItems(tags, tt.tags) {
+				t.Errorf("tags = %q, want %q", strings.Join(tags, "|"), want)
+			}
+		})
+	}
+}
+
+// sameItems 不计顺序比较两组字符串
+func sameItems(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	counts := map[string]int{}
+	for _, s := range got {
+		counts[s]++
+	}
+	for _, s := range want {
+		if counts[s] == 0 {
+			return false
+		}
+		counts[s]--
+	}
+	return true
+}
+
+func TestPostCounters(t *testing.T) {
+	r := setupTestServer(t, nil)
+	post := createPublishedPost(t, createUser(t, "author", "password123"), "Counted")
+	postURL := fmt.Sprintf("/api/posts/%d", post.ID)
+	// 浏览去重记录是进程级的，User-Agent 带上时间戳以免重复运行时被当作已浏览
+	reader := func(n int) string { return fmt.Sprintf("counter-reader-%d-%d", n, post.CreatedAt.UnixNano()) }
+
+	for _, step := range []struct {
+		path   string
+		reader int
+	}{
+		{"/like", 1}, {"/like", 2}, {"/like", 1}, {"/like", 3}, {"/unlike", 3},
+	} {
+		if w := serve(r, http.MethodPost, postURL+step.path, reader(step.reader), ""); w.Code != http.StatusOK {
+			t.Fatalf("%s by reader %d: status = %d, body %s", step.path, step.reader, w.Code, w.Body.String())
+		}
+	}
+	var stored models.Post
+	database.DB.First(&stored, post.ID)
+	if stored.LikesCount != 2 {
+		t.Errorf("likes_count = %d, want 2", stored.LikesCount)
+	}
+
+	flushViews := controllers.StartViewTracker()
+	for _, n := range []int{1, 2, 1, 3, 2} {
+		if w := serve(r, http.MethodGet, postURL, reader(n), ""); w.Code != http.StatusOK {
+			t.Fatalf("view by reader %d: status = %d", n, w.Code)
+		}
+	}
+	flushViews()
+	database.DB.First(&stored, post.ID)
+	if stored.ViewsCount != 3 {
+		t.Errorf("views_count = %d after first flush, want 3", stored.ViewsCount)
+	}
+
+	serve(r, http.MethodGet, postURL, reader(4), "")
+	flushViews()
+	database.DB.First(&stored, post.ID)
+	if stored.ViewsCount != 4 {
+		t.Errorf("views_count = %d after second flush, want 4", stored.ViewsCount)
+	}
+
+	// 公开统计不计入未公开文章的点赞和浏览
+	draft := models.Post{Title: "Draft", Slug: "draft", Content: "body", UserID: post.UserID,
+		Status: models.PostStatusDraft, LikesCount: 7, ViewsCount: 70}
+	if err := database.DB.Create(&draft).Error; err != nil {
+		t.Fatal(err)
+	}
+	w := serve(r, http.MethodGet, "/api/stats", "stats-reader", "")
+	var stats struct {
+		TotalPosts int64 `json:"total_posts"`
+		TotalLikes int64 `json:"total_likes"`
+		TotalViews int64 `json:"total_views"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("stats status = %d: %v", w.Code, err)
+	}
+	if stats.TotalPosts != 1 || stats.TotalLikes != 2 || stats.TotalViews != 4 {
+		t.Errorf("stats = %+v, want 1 post, 2 likes and 4 views", stats)
+	}
+}
+
+func TestLoginThrottleCountsAndResets(t *testing.T) {
+	r := setupTestServer(t, func(cfg *config.Config) {
+		cfg.Auth.Login.MaxFailures = 3
+		cfg.Auth.Login.IPMaxFailures = 100
+	})
+	createUser(t, "alice", "correct-password")
+	// 每次尝试前把上次失败时间往前拨，跳过失败之间的退避等待，但仍在统计窗口内
+	skipBackoff := func() {
+		database.DB.Model(&models.LoginThrottle{}).Where("locked_until IS NULL").
+			Update("last_failure_at", time.Now().Add(-2*time.Minute))
+	}
+	login := func(password string) int {
+		skipBackoff()
+		body := fmt.Sprintf(`{"username": "alice", "password": %q}`, password)
+		return serve(r, http.MethodPost, "/api/auth/login", "throttle-test", body).Code
+	}
+	throttle := func() models.LoginThrottle {
+		t.Helper()
+		var row models.LoginThrottle
+		if err := database.DB.Where("throttle_key = ?", "user:alice").First(&row).Error; err != nil {
+			t.Fatalf("load throttle: %v", err)
+		}
+		return row
+	}
+
+	for i := 0; i < 3; i++ {
+		if code := login("wrong"); code != http.StatusUnauthorized {
+			t.Fatalf("failure %d: status = %d, want 401", i+1, code)
+		}
+	}
+	row := throttle()
+	if row.Failures != 3 || row.LockedUntil == nil {
+		t.Fatalf("throttle = %+v, want 3 failures and a lock", row)
+	}
+	var lockouts int64
+	database.DB.Model(&models.AuthEvent{}).Where("event = ?", models.AuthEventLockout).Count(&lockouts)
+	if lockouts != 1 {
+		t.Errorf("lockout events = %d, want 1", lockouts)
+	}
+	if code := login("correct-password"); code == http.StatusOK {
+		t.Error("locked account accepted the correct password")
+	}
+
+	// 锁定结束后的下一次失败重新计数
+	past := time.Now().Add(-time.Hour)
+	if err := database.DB.Model(&models.LoginThrottle{}).Where("throttle_key = ?", "user:alice").
+		Updates(map[string]interface{}{"locked_until": past, "last_failure_at": past}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if code := login("wrong"); code != http.StatusUnauthorized {
+		t.Fatalf("failure after lock expired: status = %d, want 401", code)
+	}
+	if row := throttle(); row.Failures != 1 || row.LockedUntil != nil {
+		t.Errorf("throttle = %+v, want the counter reset to 1", row)
+	}
+	if code := login("correct-password"); code != http.StatusOK {
+		t.Errorf("login with correct password: status = %d, want 200", code)
+	}
+}
+
+func TestGuestIdentityUniquePerProvider(t *testing.T) {
+	setupTestServer(t, nil)
+	guests := []models.GuestUser{{Username: "first"}, {Username: "second"}}
+	if err := database.DB.Create(&guests).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	identity := models.GuestIdentity{GuestUserID: guests[0].ID, Provider: "github", Subject: "42"}
+	if err := database.DB.Create(&identity).Error; err != nil {
+		t.Fatal(err)
+	}
+	// 同一账号在不同提供方下是不同的身份
+	other := models.GuestIdentity{GuestUserID: guests[1].ID, Provider: "gitlab", Subject: "42"}
+	if err := database.DB.Create(&other).Error; err != nil {
+		t.Fatalf("same subject on another provider: %v", err)
+	}
+	duplicate := models.GuestIdentity{GuestUserID: guests[1].ID, Provider: "github", Subject: "42"}
+	if err := database.DB.Create(&duplicate).Error; err == nil {
+		t.Fatal("duplicate provider subject was accepted")
+	}
+
+	var found models.GuestIdentity
+	if err := database.DB.Where("provider = ? AND subject = ?", "github", "42").First(&found).Error; err != nil {
+		t.Fatal(err)
+	}
+	if found.GuestUserID != guests[0].ID {
+		t.Errorf("identity belongs to guest %d, want %d", found.GuestUserID, guests[0].ID)
+	}
+}
+
+func TestLoginTimingDoesNotRevealAccounts(t *testing.T) {
+	r := setupTestServer(t, nil)
+	invited := models.User{Username: "invited", Role: models.RoleAuthor}
+	if err := database.DB.Create(&invited).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 不存在和尚未激活的账号也要完整执行一次 bcrypt 校验（cost 14 约需数百毫秒），而不是立即返回
+	for _, username := range []string{"nobody", "invited"} {
+		// 清除上一次失败留下的 IP 退避
+		database.DB.Where("1 = 1").Delete(&models.LoginThrottle{})
+		start := time.Now()
+		body := fmt.Sprintf(`{"username": %q, "password": "guess"}`, username)
+		w := serve(r, http.MethodPost, "/api/auth/login", "timing-"+username, body)
+		elapsed := time.Since(start)
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("%s: status = %d, want 401", username, w.Code)
+		}
+		if elapsed < 100*time.Millisecond {
+			t.Errorf("%s: login failed in %v, without a password hash comparison", username, elapsed)
+		}
+	}
+}
+
+// rollbackTo 回滚 version 及之后的全部迁移
+func rollbackTo(t *testing.T, version string) {
+	t.Helper()
+	steps := 0
+	for _, m := range migrations.All() {
+		if m.Version >= version {
+			steps++
+		}
+	}
+	if _, err := migrations.Down(database.DB, steps); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPostRevisionVersionsAreUnique(t *testing.T) {
+	setupTestServer(t, nil)
+	post := createPublishedPost(t, createUser(t, "author", "password123"), "Revisions")
+
+	// 回滚到添加唯一索引之前，写入旧版本可能产生的重复版本号，再执行迁移
+	rollbackTo(t, "20261018130000")
+	for _, version := range []int{1, 2, 2, 3} {
+		revision := models.PostRevision{PostID: post.ID, Version: version, Title: "t", Content: "c", EditorID: post.UserID}
+		if err := database.DB.Create(&revision).Error; err != nil {
+			t.Fatalf("create revision %d: %v", version, err)
+		}
+	}
+	if _, err := migrations.Up(database.DB); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+
+	var versions []int
+	database.DB.Model(&models.PostRevision{}).Where("post_id = ?", post.ID).Order("id").Pluck("version", &versions)
+	if fmt.Sprint(versions) != "[1 2 3 4]" {
+		t.Errorf("versions = %v, want [1 2 3 4]", versions)
+	}
+	duplicate := models.PostRevision{PostID: post.ID, Version: 4, Title: "t", Content: "c", EditorID: post.UserID}
+	if err := database.DB.Create(&duplicate).Error; err == nil {
+		t.Error("duplicate revision version was accepted")
+	}
+}
+
+func TestTwoFactorChallengeIsSingleUse(t *testing.T) {
+	r := setupTestServer(t, nil)
+	user := createUser(t, "alice", "password123")
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	database.DB.Model(user).Updates(map[string]interface{}{"totp_secret": secret, "totp_enabled": true})
+
+	codes := []string{"code-one", "code-two", "code-three", "code-four"}
+	for _, code := range codes {
+		sum := sha256.Sum256([]byte(strings.ReplaceAll(code, "-", "")))
+		if err := database.DB.Create(&models.RecoveryCode{UserID: user.ID, CodeHash: hex.EncodeToString(sum[:])}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	challenge, _, err := utils.SignLoginChallenge(&utils.LoginChallenge{UserID: user.ID}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verify := func(code string) int {
+		body := fmt.Sprintf(`{"challenge_token": %q, "recovery_code": %q}`, challenge, code)
+		return serve(r, http.MethodPost, "/api/auth/login/2fa", "2fa-test", body).Code
+	}
+
+	// 同一凭证并发提交不同的恢复码，只能换到一个会话，失败的请求不消耗恢复码
+	var wg sync.WaitGroup
+	statuses := make([]int, len(codes)-1)
+	for i := range statuses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			statuses[i] = verify(codes[i])
+		}(i)
+	}
+	wg.Wait()
+	succeeded := 0
+	for _, status := range statuses {
+		if status == http.StatusOK {
+			succeeded++
+		}
+	}
+	if succeeded != 1 {
+		t.Fatalf("statuses = %v, want exactly one success", statuses)
+	}
+	var used int64
+	database.DB.Model(&models.RecoveryCode{}).Where("used_at IS NOT NULL").Count(&used)
+	if used != 1 {
+		t.Errorf("used recovery codes = %d, want 1", used)
+	}
+
+	if status := verify(codes[len(codes)-1]); status != http.StatusUnauthorized {
+		t.Errorf("reusing the challenge: status = %d, want 401", status)
+	}
+	var revoked int64
+	database.DB.Model(&models.RevokedToken{}).Count(&revoked)
+	if revoked != 1 {
+		t.Errorf("revoked challenge records = %d, want 1", revoked)
+	}
+}
+
+func TestFeedSelfLinksUseConfiguredBackendURL(t *testing.T) {
+	r := setupTestServer(t, func(cfg *config.Config) {
+		cfg.Site.BackendURL = "https://api.blog.test"
+	})
+	createPublishedPost(t, createUser(t, "author", "password123"), "Feed")
+
+	for _, path := range []string{"/feed.xml", "/atom.xml"} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Host = "attacker.test"
+		req.Header.Set("X-Forwarded-Proto", "http")
+		req.Header.Set("X-Forwarded-Host", "attacker.test")
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d", path, w.Code)
+		}
+		body := w.Body.String()
+		if strings.Contains(body, "attacker.test") || !strings.Contains(body, `href="https://api.blog.test`+path+`"`) {
+			t.Errorf("%s: self link does not use the configured backend URL:\n%s", path, body)
+		}
+	}
+}
+
+func TestLegacyDataBackfillMigrations(t *testing.T) {
+	setupTestServer(t, nil)
+	author := createUser(t, "author", "password123")
+	first := createPublishedPost(t, author, "Hello World")
+	second := createPublishedPost(t, author, "2024")
+	third := createPublishedPost(t, author, "Hello World")
+
+	// 模拟引入渲染缓存、slug 和 GuestIdentity 之前写入的数据
+	rollbackTo(t, "20261018150000")
+	for _, post := range []*models.Post{first, second, third} {
+		if err := database.DB.Model(post).UpdateColumns(map[string]interface{}{
+			"content":      "# Title\n\n## Section\n\nsome words",
+			"content_html": "",
+			"slug":         gorm.Expr("NULL"),
+		}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := database.DB.Exec("INSERT INTO tags (name) VALUES (?)", "Go Lang").Error; err != nil {
+		t.Fatal(err)
+	}
+	githubID := int64(4242)
+	guest := models.GuestUser{Username: "octocat", GitHubID: &githubID}
+	if err := database.DB.Create(&guest).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := migrations.Up(database.DB); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+
+	var posts []models.Post
+	database.DB.Order("id").Find(&posts)
+	var slugs []string
+	for _, post := range posts {
+		slugs = append(slugs, post.Slug)
+		if !strings.Contains(post.ContentHTML, "<h2") || len(post.TOC) != 1 || len(post.TOC[0].Children) != 1 || post.WordCount == 0 {
+			t.Errorf("post %d was not rendered: html %q, toc %+v", post.ID, post.ContentHTML, post.TOC)
+		}
+	}
+	if fmt.Sprint(slugs) != "[hello-world post-2024 hello-world-2]" {
+		t.Errorf("post slugs = %v", slugs)
+	}
+	if database.SearchEnabled {
+		var indexed int64
+		database.DB.Raw("SELECT COUNT(*) FROM posts_fts").Scan(&indexed)
+		if indexed != int64(len(posts)) {
+			t.Errorf("indexed posts = %d, want %d", indexed, len(posts))
+		}
+	}
+	var tag models.Tag
+	database.DB.Where("name = ?", "Go Lang").First(&tag)
+	if tag.Slug != "go-lang" {
+		t.Errorf("tag slug = %q, want go-lang", tag.Slug)
+	}
+	var identity models.GuestIdentity
+	if err := database.DB.Where("provider = ? AND subject = ?", "github", "4242").First(&identity).Error; err != nil {
+		t.Fatalf("github identity was not backfilled: %v", err)
+	}
+	if identity.GuestUserID != guest.ID {
+		t.Errorf("identity belongs to guest %d, want %d", identity.GuestUserID, guest.ID)
+	}
+
+	// 回滚只删除补建后未使用过的身份，再次执行时重新补建
+	rollbackTo(t, "20261018170000")
+	if n := countIdentities(t); n != 0 {
+		t.Errorf("identities after rollback = %d, want 0", n)
+	}
+	if _, err := migrations.Up(database.DB); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if n := countIdentities(t); n != 1 {
+		t.Errorf("identities after migrating up again = %d, want 1", n)
+	}
+}
@@ -0,0 +1,224 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gin-blog/backend/database"
+	"gin-blog/backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 登录防暴力破解：按用户名和来源 IP 分别统计失败次数。每次失败后需要等待的时间按指数增长，
+// 失败次数达到上限后锁定一段时间。检查发生在查询用户和校验 bcrypt 之前，被限制的请求不会消耗 CPU。
+const (
+	loginBackoffBase = time.Second
+	loginBackoffMax  = time.Minute
+)
+
+func usernameThrottleKey(username string) string {
+	return "user:" + strings.ToLower(strings.TrimSpace(username))
+}
+
+func ipThrottleKey(ip string) string {
+	return "ip:" + ip
+}
+
+// throttleRetryAfter 返回该记录还需等待多久才能再次尝试登录，0 表示不受限制
+func throttleRetryAfter(throttle *models.LoginThrottle, now time.Time) time.Duration {
+	if throttle.LockedUntil != nil && now.Before(*throttle.LockedUntil) {
+		return throttle.LockedUntil.Sub(now)
+	}
+	if throttle.Failures == 0 || now.Sub(throttle.LastFailureAt) > appConfig.Auth.Login.FailureWindow {
+		return 0
+	}
+	// 第 n 次失败后等待 base * 2^(n-1)，最长不超过 loginBackoffMax
+	backoff := time.Duration(float64(loginBackoffBase) * math.Pow(2, float64(throttle.Failures-1)))
+	if backoff > loginBackoffMax || backoff <= 0 {
+		backoff = loginBackoffMax
+	}
+	if wait := throttle.LastFailureAt.Add(backoff).Sub(now); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// checkLoginThrottle 在校验密码之前检查用户名和 IP 是否被限制，被限制时直接返回 429
+func checkLoginThrottle(c *gin.Context, username string) bool {
+	var throttles []models.LoginThrottle
+	keys := []string{usernameThrottleKey(username), ipThrottleKey(c.ClientIP())}
+	if err := database.DB.Where("throttle_key IN ?", keys).Find(&throttles).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return false
+	}
+
+	now := time.Now()
+	var wait time.Duration
+	for i := range throttles {
+		if w := throttleRetryAfter(&throttles[i], now); w > wait {
+			wait = w
+		}
+	}
+	if wait <= 0 {
+		return true
+	}
+
+	seconds := int(math.Ceil(wait.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       fmt.Sprintf("Too many failed login attempts, please try again in %d seconds", seconds),
+		"retry_after": seconds,
+	})
+	return false
+}
+
+// bumpThrottle 记录一次失败，返回本次是否触发了锁定。
+// 计数用 UPDATE 在数据库中原子递增，并发的失败请求不会互相覆盖；是否锁定由递增后的计数决定，
+// 设置锁定时带上 locked_until IS NULL 条件，保证同一次锁定只被一个请求触发。
+func bumpThrottle(tx *gorm.DB, key string, maxFailures int, now time.Time) (bool, error) {
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.LoginThrottle{Key: key, LastFailureAt: now}).Error; err != nil {
+		return false, err
+	}
+
+	// 超过统计窗口或锁定已结束后重新计数
+	err := tx.Model(&models.LoginThrottle{}).
+		Where("throttle_key = ? AND ((locked_until IS NOT NULL AND locked_until <= ?) OR last_failure_at < ?)",
+			key, now, now.Add(-appConfig.Auth.Login.FailureWindow)).
+		Updates(map[string]interface{}{"failures": 0, "locked_until": nil}).Error
+	if err != nil {
+		return false, err
+	}
+	err = tx.Model(&models.LoginThrottle{}).Where("throttle_key = ?", key).Updates(map[string]interface{}{
+		"failures":        gorm.Expr("failures + 1"),
+		"last_failure_at": now,
+	}).Error
+	if err != nil {
+		return false, err
+	}
+
+	var throttle models.LoginThrottle
+	if err := tx.Where("throttle_key = ?", key).First(&throttle).Error; err != nil {
+		return false, err
+	}
+	if throttle.Failures < maxFailures || throttle.LockedUntil != nil {
+		return false, nil
+	}
+	result := tx.Model(&models.LoginThrottle{}).
+		Where("throttle_key = ? AND locked_until IS NULL", key).
+		Update("locked_until", now.Add(appConfig.Auth.Login.Lockout))
+	return result.RowsAffected == 1, result.Error
+}
+
+// recordLoginFailure 记录登录失败（包括用户名不存在和两步验证码错误），达到上限时锁定并写入审计记录
+func recordLoginFailure(c *gin.Context, username string) {
+	now := time.Now()
+	ip := c.ClientIP()
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		userLocked, err := bumpThrottle(tx, usernameThrottleKey(username), appConfig.Auth.Login.MaxFailures, now)
+		if err != nil {
+			return err
+		}
+		ipLocked, err := bumpThrottle(tx, ipThrottleKey(ip), appConfig.Auth.Login.IPMaxFailures, now)
+		if err != nil {
+			return err
+		}
+
+		if userLocked {
+			if err := recordAuthEvent(tx, models.AuthEventLockout, username, ip, nil, "too many failed login attempts for username"); err != nil {
+				return err
+			}
+		}
+		if ipLocked {
+			return recordAuthEvent(tx, models.AuthEventLockout, username, ip, nil, "too many failed login attempts from IP")
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to record login failure for %q from %s: %v", username, ip, err)
+	}
+}
+
+// clearLoginFailures 登录成功后清除该用户名的失败计数
+func clearLoginFailures(username string) {
+	if err := database.DB.Where("throttle_key = ?", usernameThrottleKey(username)).Delete(&models.LoginThrottle{}).Error; err != nil {
+		log.Printf("Failed to clear login failures for %q: %v", username, err)
+	}
+}
+
+func recordAuthEvent(tx *gorm.DB, event, username, ip string, actorID *uint, detail string) error {
+	log.Printf("Security event %s: username=%q ip=%s %s", event, username, ip, detail)
+	return tx.Create(&models.AuthEvent{Event: event, Username: username, IP: ip, ActorID: actorID, Detail: detail}).Error
+}
+
+// usernameLockedUntil 返回用户名当前的锁定截止时间，未锁定时返回 nil
+func usernameLockedUntil(username string) *time.Time {
+	var throttle models.LoginThrottle
+	if err := database.DB.Where("throttle_key = ?", usernameThrottleKey(username)).First(&throttle).Error; err != nil {
+		return nil
+	}
+	if throttle.LockedUntil == nil || time.Now().After(*throttle.LockedUntil) {
+		return nil
+	}
+	return throttle.LockedUntil
+}
+
+// UnlockUser 管理员解除用户的登录锁定
+func UnlockUser(c *gin.Context) {
+	user, ok := findUser(c)
+	if !ok {
+		return
+	}
+	actorID := c.GetUint("userID")
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("throttle_key = ?", usernameThrottleKey(user.Username)).Delete(&models.LoginThrottle{}).Error; err != nil {
+			return err
+		}
+		return recordAuthEvent(tx, models.AuthEventUnlock, user.Username, c.ClientIP(), &actorID, "unlocked by administrator")
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "解除锁定失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已解除该用户的登录锁定"})
+}
+
+// GetAuthEvents 分页查看账号安全审计记录，可按 event 和 username 过滤
+func GetAuthEvents(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 200 {
+		pageSize = 50
+	}
+
+	query := database.DB.Model(&models.AuthEvent{})
+	if event := c.Query("event"); event != "" {
+		query = query.Where("event = ?", event)
+	}
+	if username := c.Query("username"); username != "" {
+		query = query.Where("username = ?", username)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取审计记录失败"})
+		return
+	}
+	var events []models.AuthEvent
+	if err := query.Order("id desc").Limit(pageSize).Offset((page - 1) * pageSize).Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取审计记录失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"events": events, "total": total, "page": page, "page_size": pageSize})
+}
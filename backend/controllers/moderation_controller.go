@@ -0,0 +1,141 @@
+package controllers
+
+import (
+	"math"
+	"net/http"
+
+	"gin-blog/backend/database"
+	"gin-blog/backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// initialCommentStatus 决定新评论的初始审核状态
+func initialCommentStatus(guestUserID uint) (string, error) {
+	if !appConfig.Comments.Moderation {
+		return models.CommentStatusApproved, nil
+	}
+	if !appConfig.Comments.TrustedBypass {
+		return models.CommentStatusPending, nil
+	}
+
+	var approved int64
+	if err := database.DB.Model(&models.Comment{}).
+		Where("guest_user_id = ? AND status = ?", guestUserID, models.CommentStatusApproved).
+		Count(&approved).Error; err != nil {
+		return "", err
+	}
+	if approved > 0 {
+		return models.CommentStatusApproved, nil
+	}
+	return models.CommentStatusPending, nil
+}
+
+// visibleCommentScope 返回当前请求可见的评论范围：
+// 拥有评论审核权限的后台用户可见全部，访客可见已通过的评论和自己待审核的评论，
+// 其他后台用户和匿名用户只能看到已通过的评论
+func visibleCommentScope(c *gin.Context) func(*gorm.DB) *gorm.DB {
+	if currentUser(c).Can(models.PermModerateComments) {
+		return func(db *gorm.DB) *gorm.DB { return db }
+	}
+	if guestUserID := readerGuestUserID(c); guestUserID != nil {
+		return func(db *gorm.DB) *gorm.DB {
+			return db.Where("(comments.status = ? OR (comments.status = ? AND comments.guest_user_id = ?))",
+				models.CommentStatusApproved, models.CommentStatusPending, *guestUserID)
+		}
+	}
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("comments.status = ?", models.CommentStatusApproved)
+	}
+}
+
+type AdminCommentListQuery struct {
+	Status   string `form:"status"`
+	PostID   uint   `form:"post_id"`
+	Page     int    `form:"page"`
+	PageSize int    `form:"page_size"`
+}
+
+// GetModerationComments 按审核状态列出评论，默认列出待审核评论
+func GetModerationComments(c *gin.Context) {
+	var q AdminCommentListQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters: " + err.Error()})
+		return
+	}
+	if q.Status == "" {
+		q.Status = models.CommentStatusPending
+	}
+	if q.Status != "all" && !models.IsValidCommentStatus(q.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment status"})
+		return
+	}
+	if q.Page <= 0 {
+		q.Page = 1
+	}
+	if q.PageSize <= 0 {
+		q.PageSize = defaultCommentPageSize
+	}
+	if q.PageSize > maxCommentPageSize {
+		q.PageSize = maxCommentPageSize
+	}
+
+	query := database.DB.Model(&models.Comment{})
+	if q.Status != "all" {
+		query = query.Where("status = ?", q.Status)
+	}
+	if q.PostID != 0 {
+		query = query.Where("post_id = ?", q.PostID)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve comments"})
+		return
+	}
+
+	var comments []models.Comment
+	if err := query.Session(&gorm.Session{}).Preload("GuestUser").Order("created_at asc").
+		Offset((q.Page - 1) * q.PageSize).Limit(q.PageSize).Find(&comments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve comments"})
+		return
+	}
+	if comments == nil {
+		comments = []models.Comment{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":        comments,
+		"total":       total,
+		"page":        q.Page,
+		"page_size":   q.PageSize,
+		"total_pages": int(math.Ceil(float64(total) / float64(q.PageSize))),
+	})
+}
+
+type ModerateCommentsInput struct {
+	IDs    []uint `json:"ids" binding:"required,min=1"`
+	Status string `json:"status" binding:"required"`
+}
+
+// ModerateComments 批量修改评论的审核状态
+func ModerateComments(c *gin.Context) {
+	var input ModerateCommentsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !models.IsValidCommentStatus(input.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment status"})
+		return
+	}
+
+	result := database.DB.Model(&models.Comment{}).Where("id IN ?", input.IDs).Update("status", input.Status)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update comments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Comments updated", "status": input.Status, "updated": result.RowsAffected})
+}
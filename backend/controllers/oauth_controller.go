@@ -2,122 +2,483 @@ package controllers
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/subtle"
+	"errors"
 	"fmt"
 	"gin-blog/backend/database"
 	"gin-blog/backend/models"
+	"gin-blog/backend/oauth"
 	"gin-blog/backend/utils"
-	"io"
+	"log"
 	"net/http"
-	"os"
-	// "strconv"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/github"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-var (
-	githubOAuthConfig *oauth2.Config
-	oauthStateString  = "random_string_for_state_validation"
+const (
+	oauthStateCookie  = "oauth_state"
+	oauthStateTTL     = 10 * time.Minute
+	oauthLoginCodeTTL = time.Minute
+	oauthLinkTTL      = 5 * time.Minute
 )
 
-func initGithubOAuthConfig() {
-	if githubOAuthConfig == nil {
-		githubOAuthConfig = &oauth2.Config{
-			ClientID:     os.Getenv("GITHUB_CLIENT_ID"),
-			ClientSecret: os.Getenv("GITHUB_CLIENT_SECRET"),
-			RedirectURL:  os.Getenv("GITHUB_REDIRECT_URL"),
-			Scopes:       []string{"read:user", "user:email"},
-			Endpoint:     github.Endpoint,
+var errIdentityLinked = errors.New("identity is already linked to another guest")
+
+func frontendBaseURL() string {
+	return appConfig.Site.FrontendURL
+}
+
+// allowedRedirectTarget 校验登录完成后的跳转目标：站内路径总是允许，
+// 绝对地址必须匹配 FRONTEND_URL 或 OAUTH_REDIRECT_ALLOWLIST（逗号分隔）中的某一项
+func allowedRedirectTarget(target string) (string, bool) {
+	if target == "" {
+		return "/", true
+	}
+	if strings.HasPrefix(target, "/") && !strings.HasPrefix(target, "//") && !strings.HasPrefix(target, "/\\") {
+		return target, true
+	}
+
+	parsed, err := url.Parse(target)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", false
+	}
+	allowlist := append([]string{frontendBaseURL()}, appConfig.OAuth.RedirectAllowlist...)
+	for _, entry := range allowlist {
+		allowed, err := url.Parse(strings.TrimSpace(entry))
+		if err != nil || allowed.Host == "" {
+			continue
+		}
+		if parsed.Scheme != allowed.Scheme || parsed.Host != allowed.Host {
+			continue
+		}
+		prefix := strings.TrimRight(allowed.Path, "/")
+		if prefix == "" || parsed.Path == prefix || strings.HasPrefix(parsed.Path, prefix+"/") {
+			return target, true
 		}
 	}
+	return "", false
 }
 
-func HandleGitHubLogin(c *gin.Context) {
-	initGithubOAuthConfig()
-	url := githubOAuthConfig.AuthCodeURL(oauthStateString, oauth2.AccessTypeOffline)
-	c.Redirect(http.StatusTemporaryRedirect, url)
+// redirectOAuthError 跳回前端回调页并只带上错误码，详细错误只记录在服务端日志中
+func redirectOAuthError(c *gin.Context, code string, err error) {
+	if err != nil {
+		log.Printf("OAuth login failed (%s): %v", code, err)
+	}
+	c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("%s/auth/callback?error=%s", frontendBaseURL(), url.QueryEscape(code)))
 }
 
-type GitHubUserResponse struct {
-	ID        int64  `json:"id"`
-	Login     string `json:"login"`
-	AvatarURL string `json:"avatar_url"`
-	Name      string `json:"name"`
-	Email     string `json:"email"`
+func setOAuthStateCookie(c *gin.Context, value string, maxAge int) {
+	// 后端通过 https 对外提供服务时 Cookie 只通过 https 发送；SameSite=Lax 保证从提供方跳回时能带上 Cookie
+	secure := c.Request.TLS != nil || strings.HasPrefix(backendURL(), "https://")
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oauthStateCookie, value, maxAge, "/api/auth", "", secure, true)
 }
 
-func HandleGitHubCallback(c *gin.Context) {
-	initGithubOAuthConfig()
-	frontendURL := os.Getenv("FRONTEND_URL")
-	if frontendURL == "" {
-		frontendURL = "http://localhost:3000"
+func findOAuthProvider(c *gin.Context) (oauth.Provider, bool) {
+	provider, ok := oauth.Get(c.Param("provider"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown login provider"})
 	}
+	return provider, ok
+}
+
+// GetOAuthProviders 列出已启用的第三方登录方式
+func GetOAuthProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": oauth.Names()})
+}
 
-	state := c.Query("state")
-	if state != oauthStateString {
-		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("%s/auth/callback#error=invalid_state", frontendURL))
+// HandleOAuthLogin 生成随机 state 和 PKCE verifier，写入签名的短期 Cookie 后跳转到提供方授权页。
+// 可选的 redirect 参数指定登录完成后前端跳转的位置，link_ticket 参数用于为已登录访客关联新的登录方式。
+func HandleOAuthLogin(c *gin.Context) {
+	provider, ok := findOAuthProvider(c)
+	if !ok {
 		return
 	}
 
-	code := c.Query("code")
-	token, err := githubOAuthConfig.Exchange(context.Background(), code)
+	redirect, ok := allowedRedirectTarget(c.Query("redirect"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Redirect target is not allowed"})
+		return
+	}
+
+	var linkGuestID uint
+	if ticketStr := c.Query("link_ticket"); ticketStr != "" {
+		ticket, err := utils.ParseOAuthLinkTicket(ticketStr)
+		if err != nil || ticket.Provider != provider.Name() || ticket.ID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired link ticket"})
+			return
+		}
+		// 凭证只能使用一次：写入 jti 成功的请求才能继续
+		result := database.DB.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.RevokedToken{JTI: ticket.ID, ExpiresAt: ticket.ExpiresAt.Time})
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start OAuth login"})
+			return
+		}
+		if result.RowsAffected == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired link ticket"})
+			return
+		}
+		linkGuestID = ticket.GuestUserID
+	}
+
+	state, err := newSecureToken()
 	if err != nil {
-		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("%s/auth/callback#error=failed_to_exchange_code&details=%s", frontendURL, err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start OAuth login"})
 		return
 	}
+	verifier := oauth2.GenerateVerifier()
+	signed, err := utils.SignOAuthState(&utils.OAuthState{
+		Provider:    provider.Name(),
+		State:       state,
+		Verifier:    verifier,
+		Redirect:    redirect,
+		LinkGuestID: linkGuestID,
+	}, oauthStateTTL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start OAuth login"})
+		return
+	}
+	setOAuthStateCookie(c, signed, int(oauthStateTTL.Seconds()))
+
+	authURL := provider.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.S256ChallengeOption(verifier))
+	c.Redirect(http.StatusTemporaryRedirect, authURL)
+}
+
+// upsertGuestIdentity 按提供方账号查找或创建访客，并更新账号信息
+func upsertGuestIdentity(providerName string, profile *oauth.Profile, token *oauth2.Token) (*models.GuestUser, error) {
+	var guestUser models.GuestUser
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var identity models.GuestIdentity
+		err := tx.Where("provider = ? AND subject = ?", providerName, profile.Subject).First(&identity).Error
+		switch {
+		case err == nil:
+			err = tx.First(&guestUser, identity.GuestUserID).Error
+		case err == gorm.ErrRecordNotFound:
+			guestUser = models.GuestUser{Username: profile.Username, AvatarURL: profile.AvatarURL}
+			err = tx.Create(&guestUser).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		identity.GuestUserID = guestUser.ID
+		identity.Provider = providerName
+		identity.Subject = profile.Subject
+		identity.Username = profile.Username
+		identity.Email = profile.Email
+		identity.AvatarURL = profile.AvatarURL
+		identity.AccessToken = token.AccessToken
+		if err := tx.Save(&identity).Error; err != nil {
+			return err
+		}
+
+		// 访客的显示名称和头像跟随最近一次登录使用的账号
+		if guestUser.Username != profile.Username || guestUser.AvatarURL != profile.AvatarURL {
+			guestUser.Username = profile.Username
+			guestUser.AvatarURL = profile.AvatarURL
+			return tx.Model(&guestUser).Updates(map[string]interface{}{
+				"username":   profile.Username,
+				"avatar_url": profile.AvatarURL,
+			}).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &guestUser, nil
+}
+
+// identityLinkedElsewhere 检查提供方账号是否已属于 guestID 以外的访客
+func identityLinkedElsewhere(tx *gorm.DB, providerName, subject string, guestID uint) (*models.GuestIdentity, error) {
+	var identity models.GuestIdentity
+	err := tx.Where("provider = ? AND subject = ?", providerName, subject).First(&identity).Error
+	if err == gorm.ErrRecordNotFound {
+		return &identity, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if identity.GuestUserID != guestID {
+		return nil, errIdentityLinked
+	}
+	return &identity, nil
+}
 
-	client := githubOAuthConfig.Client(context.Background(), token)
-	resp, err := client.Get("https://api.github.com/user")
+// linkGuestIdentity 把授权码中记录的提供方账号关联到发起关联的访客，账号已属于其他访客时返回 errIdentityLinked
+func linkGuestIdentity(record *models.OAuthLoginCode) (*models.GuestUser, error) {
+	var guestUser models.GuestUser
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		identity, err := identityLinkedElsewhere(tx, record.Provider, record.Subject, record.LinkGuestID)
+		if err != nil {
+			return err
+		}
+		if err := tx.First(&guestUser, record.LinkGuestID).Error; err != nil {
+			return err
+		}
+
+		identity.GuestUserID = guestUser.ID
+		identity.Provider = record.Provider
+		identity.Subject = record.Subject
+		identity.Username = record.Username
+		identity.Email = record.Email
+		identity.AvatarURL = record.AvatarURL
+		identity.AccessToken = record.AccessToken
+		return tx.Save(identity).Error
+	})
 	if err != nil {
-		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("%s/auth/callback#error=failed_to_get_user_info&details=%s", frontendURL, err.Error()))
+		return nil, err
+	}
+	return &guestUser, nil
+}
+
+func HandleOAuthCallback(c *gin.Context) {
+	// state Cookie 只能使用一次，无论成功与否都立即清除
+	cookie, cookieErr := c.Cookie(oauthStateCookie)
+	setOAuthStateCookie(c, "", -1)
+
+	provider, ok := oauth.Get(c.Param("provider"))
+	if !ok {
+		redirectOAuthError(c, "unknown_provider", nil)
+		return
+	}
+	if cookieErr != nil {
+		redirectOAuthError(c, "invalid_state", cookieErr)
+		return
+	}
+	savedState, err := utils.ParseOAuthState(cookie)
+	if err != nil {
+		redirectOAuthError(c, "invalid_state", err)
+		return
+	}
+	if savedState.Provider != provider.Name() ||
+		subtle.ConstantTimeCompare([]byte(c.Query("state")), []byte(savedState.State)) != 1 {
+		redirectOAuthError(c, "invalid_state", nil)
+		return
+	}
+	if c.Query("error") != "" {
+		redirectOAuthError(c, "access_denied", fmt.Errorf("%s: %s", c.Query("error"), c.Query("error_description")))
 		return
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	ctx := context.Background()
+	token, err := provider.Exchange(ctx, c.Query("code"), oauth2.VerifierOption(savedState.Verifier))
 	if err != nil {
-		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("%s/auth/callback#error=failed_to_read_user_response&details=%s", frontendURL, err.Error()))
+		redirectOAuthError(c, "failed_to_exchange_code", err)
 		return
 	}
 
-	var ghUser GitHubUserResponse
-	if err := json.Unmarshal(body, &ghUser); err != nil {
-		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("%s/auth/callback#error=failed_to_parse_user_json&details=%s", frontendURL, err.Error()))
+	profile, err := provider.FetchProfile(ctx, token)
+	if err != nil {
+		redirectOAuthError(c, "failed_to_get_user_info", err)
 		return
 	}
 
-	var guestUser models.GuestUser
-	err = database.DB.Where("git_hub_id = ?", ghUser.ID).First(&guestUser).Error
-	if err != nil { // User not found, create new
-		guestUser = models.GuestUser{
-			GitHubID:    ghUser.ID,
-			Username:    ghUser.Login,
-			AvatarURL:   ghUser.AvatarURL,
-			AccessToken: token.AccessToken,
-		}
-		if err := database.DB.Create(&guestUser).Error; err != nil {
-			c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("%s/auth/callback#error=failed_to_create_guest_user&details=%s", frontendURL, err.Error()))
+	loginCode, err := newSecureToken()
+	if err != nil {
+		redirectOAuthError(c, "failed_to_generate_login_code", err)
+		return
+	}
+	record := models.OAuthLoginCode{
+		CodeHash:  hashToken(loginCode),
+		Provider:  provider.Name(),
+		Redirect:  savedState.Redirect,
+		ExpiresAt: time.Now().Add(oauthLoginCodeTTL),
+	}
+
+	if savedState.LinkGuestID != 0 {
+		// 关联流程不在回调中写入身份：完成回调的浏览器不一定属于发起关联的访客，
+		// 先把账号信息随授权码保存，由该访客本人兑换授权码时再完成关联
+		if _, err := identityLinkedElsewhere(database.DB, provider.Name(), profile.Subject, savedState.LinkGuestID); err == errIdentityLinked {
+			redirectOAuthError(c, "identity_already_linked", nil)
+			return
+		} else if err != nil {
+			redirectOAuthError(c, "failed_to_save_guest_user", err)
 			return
 		}
-	} else { // User found, update if necessary
-		guestUser.Username = ghUser.Login
-		guestUser.AvatarURL = ghUser.AvatarURL
-		guestUser.AccessToken = token.AccessToken // Update access token
-		if err := database.DB.Save(&guestUser).Error; err != nil {
-			c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("%s/auth/callback#error=failed_to_update_guest_user&details=%s", frontendURL, err.Error()))
+		record.LinkGuestID = savedState.LinkGuestID
+		record.Subject = profile.Subject
+		record.Username = profile.Username
+		record.Email = profile.Email
+		record.AvatarURL = profile.AvatarURL
+		record.AccessToken = token.AccessToken
+	} else {
+		guestUser, err := upsertGuestIdentity(provider.Name(), profile, token)
+		if err != nil {
+			redirectOAuthError(c, "failed_to_save_guest_user", err)
+			return
+		}
+		record.GuestUserID = guestUser.ID
+	}
+	if err := database.DB.Create(&record).Error; err != nil {
+		redirectOAuthError(c, "failed_to_generate_login_code", err)
+		return
+	}
+
+	c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("%s/auth/callback?code=%s", frontendBaseURL(), url.QueryEscape(loginCode)))
+}
+
+type ExchangeOAuthCodeInput struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// ExchangeOAuthCode 前端用一次性授权码换取访客的访问令牌和刷新令牌，授权码使用后立即作废。
+// 关联登录方式的授权码在这里完成关联，要求请求携带发起关联的访客的访问令牌。
+func ExchangeOAuthCode(c *gin.Context) {
+	var input ExchangeOAuthCodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var record models.OAuthLoginCode
+	if err := database.DB.Where("code_hash = ?", hashToken(input.Code)).First(&record).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired login code"})
 			return
 		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	// 删除成功的请求才能使用授权码，并发重复提交时只有一个会成功
+	result := database.DB.Where("id = ?", record.ID).Delete(&models.OAuthLoginCode{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if result.RowsAffected == 0 || time.Now().After(record.ExpiresAt) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired login code"})
+		return
+	}
+	if err := database.DB.Where("expires_at < ?", time.Now()).Delete(&models.OAuthLoginCode{}).Error; err != nil {
+		log.Printf("Failed to prune expired OAuth login codes: %v", err)
+	}
+
+	var guestUser *models.GuestUser
+	if record.LinkGuestID != 0 {
+		// 关联授权码只能由发起关联的访客携带自己的访问令牌兑换
+		if c.GetString("userType") != models.SubjectGuest || c.GetUint("guestUserID") != record.LinkGuestID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Login code was issued to a different session"})
+			return
+		}
+		linked, err := linkGuestIdentity(&record)
+		if err == errIdentityLinked {
+			c.JSON(http.StatusConflict, gin.H{"error": "identity_already_linked"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link login provider"})
+			return
+		}
+		guestUser = linked
+	} else {
+		guestUser = &models.GuestUser{}
+		if err := database.DB.First(guestUser, record.GuestUserID).Error; err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired login code"})
+			return
+		}
+	}
+
+	subject := guestSubject(guestUser, record.Provider)
+	tokens, _, err := issueSession(database.DB, c, subject, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	response := sessionResponse(subject, tokens)
+	response["redirect"] = record.Redirect
+	c.JSON(http.StatusOK, response)
+}
+
+// requireGuest 关联登录方式相关的接口只对访客开放
+func requireGuest(c *gin.Context) (uint, bool) {
+	if c.GetString("userType") != models.SubjectGuest {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only guest users can manage login providers"})
+		return 0, false
+	}
+	return c.GetUint("guestUserID"), true
+}
+
+// CreateOAuthLinkTicket 已登录访客发起关联新登录方式，返回带短期凭证的登录地址
+func CreateOAuthLinkTicket(c *gin.Context) {
+	provider, ok := findOAuthProvider(c)
+	if !ok {
+		return
+	}
+	guestID, ok := requireGuest(c)
+	if !ok {
+		return
 	}
 
-	jwtToken, err := utils.GenerateToken(guestUser.ID, guestUser.Username, guestUser.AvatarURL, "guest")
+	ticket, err := utils.SignOAuthLinkTicket(&utils.OAuthLinkTicket{
+		Provider:    provider.Name(),
+		GuestUserID: guestID,
+	}, oauthLinkTTL)
 	if err != nil {
-		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("%s/auth/callback#error=failed_to_generate_jwt&details=%s", frontendURL, err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create link ticket"})
 		return
 	}
 
-	redirectURL := fmt.Sprintf("%s/auth/callback#token=%s&guest_id=%d&username=%s&avatar_url=%s&type=guest",
-		frontendURL, jwtToken, guestUser.ID, guestUser.Username, guestUser.AvatarURL)
-	c.Redirect(http.StatusTemporaryRedirect, redirectURL)
-}
\ No newline at end of file
+	query := url.Values{"link_ticket": {ticket}}
+	if c.Query("redirect") != "" {
+		query.Set("redirect", c.Query("redirect"))
+	}
+	c.JSON(http.StatusOK, gin.H{"url": backendURL() + "/api/auth/" + provider.Name() + "/login?" + query.Encode()})
+}
+
+// GetGuestIdentities 列出当前访客已关联的登录方式
+func GetGuestIdentities(c *gin.Context) {
+	guestID, ok := requireGuest(c)
+	if !ok {
+		return
+	}
+	var identities []models.GuestIdentity
+	if err := database.DB.Where("guest_user_id = ?", guestID).Order("id asc").Find(&identities).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	c.JSON(http.StatusOK, identities)
+}
+
+// UnlinkGuestIdentity 取消关联一个登录方式，访客至少需要保留一个
+func UnlinkGuestIdentity(c *gin.Context) {
+	guestID, ok := requireGuest(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid identity ID"})
+		return
+	}
+
+	var count int64
+	if err := database.DB.Model(&models.GuestIdentity{}).Where("guest_user_id = ?", guestID).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if count <= 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one login provider must remain linked"})
+		return
+	}
+
+	result := database.DB.Where("id = ? AND guest_user_id = ?", uint(id), guestID).Delete(&models.GuestIdentity{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Identity not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Login provider unlinked"})
+}
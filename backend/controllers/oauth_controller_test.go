@@ -0,0 +1,449 @@
+package controllers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gin-blog/backend/config"
+	"gin-blog/backend/database"
+	"gin-blog/backend/models"
+	"gin-blog/backend/oauth"
+	"gin-blog/backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/oauth2"
+)
+
+const (
+	testProvider    = "testidp"
+	testFrontendURL = "http://frontend.test"
+)
+
+// idpUser 测试提供方返回的用户资料
+type idpUser struct {
+	Sub               string `json:"sub"`
+	PreferredUsername string `json:"preferred_username"`
+	Email             string `json:"email"`
+	Picture           string `json:"picture"`
+}
+
+// fakeIdP 模拟 OIDC 提供方。测试代替浏览器完成授权：authorize 记录授权地址中的 PKCE challenge
+// 并返回授权码，令牌接口只有在 code_verifier 与 challenge 匹配时才发放访问令牌。
+type fakeIdP struct {
+	*httptest.Server
+	mu         sync.Mutex
+	codes      map[string]idpGrant
+	tokens     map[string]idpUser
+	tokenCalls int
+}
+
+type idpGrant struct {
+	challenge string
+	user      idpUser
+}
+
+func newFakeIdP(t *testing.T) *fakeIdP {
+	idp := &fakeIdP{codes: map[string]idpGrant{}, tokens: map[string]idpUser{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.URL,
+			"authorization_endpoint": idp.URL + "/authorize",
+			"token_endpoint":         idp.URL + "/token",
+			"userinfo_endpoint":      idp.URL + "/userinfo",
+		})
+	})
+	mux.HandleFunc("/token", idp.handleToken)
+	mux.HandleFunc("/userinfo", idp.handleUserinfo)
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+// authorize 模拟用户在提供方同意授权，返回回调地址中的授权码
+func (idp *fakeIdP) authorize(t *testing.T, authURL url.Values, user idpUser) string {
+	t.Helper()
+	if authURL.Get("code_challenge_method") != "S256" || authURL.Get("code_challenge") == "" {
+		t.Fatalf("authorize URL has no S256 PKCE challenge: %v", authURL)
+	}
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	code := fmt.Sprintf("code-%d", len(idp.codes)+1)
+	idp.codes[code] = idpGrant{challenge: authURL.Get("code_challenge"), user: user}
+	return code
+}
+
+func (idp *fakeIdP) handleToken(w http.ResponseWriter, r *http.Request) {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.tokenCalls++
+
+	r.ParseForm()
+	grant, ok := idp.codes[r.PostForm.Get("code")]
+	if !ok || oauth2.S256ChallengeFromVerifier(r.PostForm.Get("code_verifier")) != grant.challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+	delete(idp.codes, r.PostForm.Get("code"))
+	token := "token-" + grant.user.Sub
+	idp.tokens[token] = grant.user
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"access_token": token, "token_type": "Bearer", "expires_in": 3600})
+}
+
+func (idp *fakeIdP) handleUserinfo(w http.ResponseWriter, r *http.Request) {
+	idp.mu.Lock()
+	user, ok := idp.tokens[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+	idp.mu.Unlock()
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	json.NewEncoder(w).Encode(user)
+}
+
+// setupOAuthTest 启动测试服务并注册指向测试提供方的 OIDC 登录方式
+func setupOAuthTest(t *testing.T) (*gin.Engine, *fakeIdP) {
+	t.Helper()
+	r := setupTestServer(t, func(cfg *config.Config) {
+		cfg.Site.FrontendURL = testFrontendURL
+	})
+
+	idp := newFakeIdP(t)
+	provider, err := oauth.NewOIDC(context.Background(), testProvider, oauth2.Config{
+		ClientID:     "client-id",
+		ClientSecret: "client-secret",
+		RedirectURL:  "http://blog.test/api/auth/" + testProvider + "/callback",
+	}, idp.URL)
+	if err != nil {
+		t.Fatalf("NewOIDC: %v", err)
+	}
+	oauth.Register(provider)
+	return r, idp
+}
+
+// startLogin 请求登录接口，返回 state Cookie 和提供方授权地址中的参数
+func startLogin(t *testing.T, r *gin.Engine, query url.Values) (*http.Cookie, url.Values) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/auth/"+testProvider+"/login?"+query.Encode(), nil))
+	if w.Code != http.StatusTemporaryRedirect {
+		t.Fatalf("login status = %d, body %s", w.Code, w.Body.String())
+	}
+	location, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("invalid login redirect: %v", err)
+	}
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == "oauth_state" && cookie.Value != "" {
+			return cookie, location.Query()
+		}
+	}
+	t.Fatal("login did not set the oauth_state cookie")
+	return nil, nil
+}
+
+// finishCallback 请求回调接口，返回跳转到前端的地址
+func finishCallback(t *testing.T, r *gin.Engine, cookie *http.Cookie, query url.Values) *url.URL {
+	t.Helper()
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/"+testProvider+"/callback?"+query.Encode(), nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusTemporaryRedirect {
+		t.Fatalf("callback status = %d, body %s", w.Code, w.Body.String())
+	}
+	location, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("invalid callback redirect: %v", err)
+	}
+	if got := location.Scheme + "://" + location.Host + location.Path; got != testFrontendURL+"/auth/callback" {
+		t.Fatalf("callback redirected to %s", location)
+	}
+	return location
+}
+
+// exchangeCode 兑换一次性授权码，token 非空时携带该访问令牌
+func exchangeCode(r *gin.Engine, code, token string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	body := strings.NewReader(fmt.Sprintf(`{"code": %q}`, code))
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/oauth/exchange", body)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	r.ServeHTTP(w, req)
+	return w
+}
+
+type sessionResponse struct {
+	Token    string `json:"token"`
+	GuestID  uint   `json:"guest_id"`
+	Username string `json:"username"`
+	Redirect string `json:"redirect"`
+}
+
+// loginCodeFor 完成提供方授权和回调，返回发给前端的一次性授权码
+func loginCodeFor(t *testing.T, r *gin.Engine, idp *fakeIdP, query url.Values, user idpUser) string {
+	t.Helper()
+	cookie, authURL := startLogin(t, r, query)
+	code := idp.authorize(t, authURL, user)
+	location := finishCallback(t, r, cookie, url.Values{"code": {code}, "state": {authURL.Get("state")}})
+	loginCode := location.Query().Get("code")
+	if loginCode == "" {
+		t.Fatalf("callback returned error %q", location.Query().Get("error"))
+	}
+	return loginCode
+}
+
+func decodeSession(t *testing.T, w *httptest.ResponseRecorder) sessionResponse {
+	t.Helper()
+	if w.Code != http.StatusOK {
+		t.Fatalf("exchange status = %d, body %s", w.Code, w.Body.String())
+	}
+	var session sessionResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &session); err != nil {
+		t.Fatalf("invalid exchange response: %v", err)
+	}
+	return session
+}
+
+// loginAs 完成一次完整的第三方登录，返回换取到的会话
+func loginAs(t *testing.T, r *gin.Engine, idp *fakeIdP, query url.Values, user idpUser) sessionResponse {
+	t.Helper()
+	return decodeSession(t, exchangeCode(r, loginCodeFor(t, r, idp, query, user), ""))
+}
+
+func countIdentities(t *testing.T) int64 {
+	t.Helper()
+	var count int64
+	if err := database.DB.Model(&models.GuestIdentity{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestOAuthLoginCreatesGuestAndIssuesToken(t *testing.T) {
+	r, idp := setupOAuthTest(t)
+
+	cookie, authURL := startLogin(t, r, url.Values{"redirect": {"/posts/hello"}})
+	if authURL.Get("state") == "" || authURL.Get("client_id") != "client-id" {
+		t.Fatalf("unexpected authorize parameters: %v", authURL)
+	}
+	code := idp.authorize(t, authURL, idpUser{Sub: "user-1", PreferredUsername: "alice", Email: "alice@example.com"})
+	location := finishCallback(t, r, cookie, url.Values{"code": {code}, "state": {authURL.Get("state")}})
+	loginCode := location.Query().Get("code")
+	if loginCode == "" {
+		t.Fatalf("callback returned error %q", location.Query().Get("error"))
+	}
+
+	var identity models.GuestIdentity
+	if err := database.DB.Where("provider = ? AND subject = ?", testProvider, "user-1").First(&identity).Error; err != nil {
+		t.Fatalf("guest identity was not created: %v", err)
+	}
+	if identity.Username != "alice" || identity.Email != "alice@example.com" || identity.AccessToken != "token-user-1" {
+		t.Errorf("identity = %+v", identity)
+	}
+
+	w := exchangeCode(r, loginCode, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("exchange status = %d, body %s", w.Code, w.Body.String())
+	}
+	var session sessionResponse
+	json.Unmarshal(w.Body.Bytes(), &session)
+	if session.GuestID != identity.GuestUserID || session.Username != "alice" || session.Redirect != "/posts/hello" {
+		t.Errorf("session = %+v, identity guest %d", session, identity.GuestUserID)
+	}
+	claims, err := utils.ValidateToken(session.Token)
+	if err != nil {
+		t.Fatalf("issued token is invalid: %v", err)
+	}
+	if claims.GuestUserID != identity.GuestUserID || claims.Provider != testProvider {
+		t.Errorf("claims guest = %d provider = %q, want %d %q", claims.GuestUserID, claims.Provider, identity.GuestUserID, testProvider)
+	}
+
+	// 授权码只能使用一次
+	if w := exchangeCode(r, loginCode, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("second exchange status = %d, want 401", w.Code)
+	}
+}
+
+func TestOAuthLoginReusesExistingIdentity(t *testing.T) {
+	r, idp := setupOAuthTest(t)
+
+	first := loginAs(t, r, idp, nil, idpUser{Sub: "user-1", PreferredUsername: "alice"})
+	second := loginAs(t, r, idp, nil, idpUser{Sub: "user-1", PreferredUsername: "alice-renamed", Picture: "https://avatars.test/a"})
+	if first.GuestID != second.GuestID {
+		t.Fatalf("second login created guest %d, want %d", second.GuestID, first.GuestID)
+	}
+	if n := countIdentities(t); n != 1 {
+		t.Fatalf("identities = %d, want 1", n)
+	}
+
+	var guest models.GuestUser
+	database.DB.First(&guest, first.GuestID)
+	if guest.Username != "alice-renamed" || guest.AvatarURL != "https://avatars.test/a" {
+		t.Errorf("guest profile was not refreshed: %+v", guest)
+	}
+}
+
+func TestOAuthCallbackRejectsInvalidState(t *testing.T) {
+	r, idp := setupOAuthTest(t)
+
+	tests := []struct {
+		name   string
+		cookie func(cookie *http.Cookie) *http.Cookie
+		state  func(state string) string
+	}{
+		{
+			name:   "missing cookie",
+			cookie: func(*http.Cookie) *http.Cookie { return nil },
+			state:  func(state string) string { return state },
+		},
+		{
+			name:   "state mismatch",
+			cookie: func(cookie *http.Cookie) *http.Cookie { return cookie },
+			state:  func(string) string { return "forged-state" },
+		},
+		{
+			name: "tampered cookie",
+			cookie: func(cookie *http.Cookie) *http.Cookie {
+				return &http.Cookie{Name: cookie.Name, Value: cookie.Value + "x"}
+			},
+			state: func(state string) string { return state },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cookie, authURL := startLogin(t, r, nil)
+			code := idp.authorize(t, authURL, idpUser{Sub: "user-1", PreferredUsername: "alice"})
+			location := finishCallback(t, r, tt.cookie(cookie), url.Values{"code": {code}, "state": {tt.state(authURL.Get("state"))}})
+			if got := location.Query().Get("error"); got != "invalid_state" {
+				t.Errorf("error = %q, want invalid_state", got)
+			}
+			if location.Query().Get("code") != "" {
+				t.Error("callback issued a login code for an invalid state")
+			}
+		})
+	}
+	if idp.tokenCalls != 0 {
+		t.Errorf("token endpoint called %d times, want 0", idp.tokenCalls)
+	}
+	if n := countIdentities(t); n != 0 {
+		t.Errorf("identities = %d, want 0", n)
+	}
+}
+
+func TestOAuthLinkIdentityToGuest(t *testing.T) {
+	r, idp := setupOAuthTest(t)
+
+	alice := loginAs(t, r, idp, nil, idpUser{Sub: "user-1", PreferredUsername: "alice"})
+	linkQuery := func(session sessionResponse) url.Values {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/"+testProvider+"/link", nil)
+		req.Header.Set("Authorization", "Bearer "+session.Token)
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("link status = %d, body %s", w.Code, w.Body.String())
+		}
+		var resp struct {
+			URL string `json:"url"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		linkURL, err := url.Parse(resp.URL)
+		if err != nil || linkURL.Query().Get("link_ticket") == "" {
+			t.Fatalf("invalid link URL %q", resp.URL)
+		}
+		return linkURL.Query()
+	}
+
+	bob := loginAs(t, r, idp, nil, idpUser{Sub: "user-3", PreferredUsername: "bob"})
+	work := idpUser{Sub: "user-2", PreferredUsername: "alice-work"}
+
+	// 回调本身不写入身份，授权码只能由发起关联的访客携带自己的 token 兑换
+	for name, token := range map[string]string{"anonymous": "", "other guest": bob.Token} {
+		code := loginCodeFor(t, r, idp, linkQuery(alice), work)
+		if n := countIdentities(t); n != 2 {
+			t.Fatalf("%s: callback wrote an identity before the exchange, identities = %d", name, n)
+		}
+		if w := exchangeCode(r, code, token); w.Code != http.StatusForbidden {
+			t.Errorf("%s: exchange status = %d, want 403", name, w.Code)
+		}
+		if w := exchangeCode(r, code, alice.Token); w.Code != http.StatusUnauthorized {
+			t.Errorf("%s: rejected code could be reused, status = %d", name, w.Code)
+		}
+	}
+	if n := countIdentities(t); n != 2 {
+		t.Fatalf("rejected link exchanges created identities, identities = %d", n)
+	}
+
+	// 关联凭证只能使用一次
+	ticketQuery := linkQuery(alice)
+	linked := decodeSession(t, exchangeCode(r, loginCodeFor(t, r, idp, ticketQuery, work), alice.Token))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/auth/"+testProvider+"/login?"+ticketQuery.Encode(), nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("reused link ticket status = %d, want 400", w.Code)
+	}
+
+	if linked.GuestID != alice.GuestID {
+		t.Fatalf("linked login returned guest %d, want %d", linked.GuestID, alice.GuestID)
+	}
+	var identity models.GuestIdentity
+	if err := database.DB.Where("provider = ? AND subject = ?", testProvider, "user-2").First(&identity).Error; err != nil {
+		t.Fatalf("linked identity was not created: %v", err)
+	}
+	if identity.GuestUserID != alice.GuestID {
+		t.Errorf("linked identity belongs to guest %d, want %d", identity.GuestUserID, alice.GuestID)
+	}
+	// 关联登录方式不改变访客的显示名称
+	var guest models.GuestUser
+	database.DB.First(&guest, alice.GuestID)
+	if guest.Username != "alice" {
+		t.Errorf("guest username = %q, want alice", guest.Username)
+	}
+
+	// 已属于其他访客的账号不能再被关联
+	cookie, authURL := startLogin(t, r, linkQuery(bob))
+	code := idp.authorize(t, authURL, idpUser{Sub: "user-2", PreferredUsername: "alice-work"})
+	location := finishCallback(t, r, cookie, url.Values{"code": {code}, "state": {authURL.Get("state")}})
+	if got := location.Query().Get("error"); got != "identity_already_linked" {
+		t.Errorf("error = %q, want identity_already_linked", got)
+	}
+	database.DB.First(&identity, identity.ID)
+	if identity.GuestUserID != alice.GuestID {
+		t.Errorf("identity moved to guest %d", identity.GuestUserID)
+	}
+}
+
+func TestOAuthExchangeRejectsInvalidCodes(t *testing.T) {
+	r, idp := setupOAuthTest(t)
+
+	if w := exchangeCode(r, "unknown-code", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("unknown code status = %d, want 401", w.Code)
+	}
+
+	cookie, authURL := startLogin(t, r, nil)
+	code := idp.authorize(t, authURL, idpUser{Sub: "user-1", PreferredUsername: "alice"})
+	location := finishCallback(t, r, cookie, url.Values{"code": {code}, "state": {authURL.Get("state")}})
+	loginCode := location.Query().Get("code")
+	if err := database.DB.Model(&models.OAuthLoginCode{}).Where("1 = 1").
+		Update("expires_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if w := exchangeCode(r, loginCode, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("expired code status = %d, want 401", w.Code)
+	}
+}
@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"gin-blog/backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// currentUser 返回认证中间件加载的后台用户，访客或匿名请求返回 nil
+func currentUser(c *gin.Context) *models.User {
+	if value, ok := c.Get("currentUser"); ok {
+		if user, ok := value.(*models.User); ok {
+			return user
+		}
+	}
+	return nil
+}
+
+// canEditPost 判断用户能否修改或删除文章：编辑和所有者可以处理任何文章，
+// 作者只能处理自己的文章，投稿者只能处理自己尚未发布的草稿。
+func canEditPost(user *models.User, post *models.Post) bool {
+	if user == nil || user.Disabled {
+		return false
+	}
+	if user.Can(models.PermEditAnyPost) {
+		return true
+	}
+	if post.UserID != user.ID {
+		return false
+	}
+	if !user.Can(models.PermPublishPost) {
+		return post.Status == models.PostStatusDraft
+	}
+	return true
+}
+
+// canViewPost 判断用户能否查看未公开的文章及其历史版本：能修改该文章的用户，
+// 以及文章作者本人（例如文章已发布、自己不能再修改的投稿者）。
+func canViewPost(user *models.User, post *models.Post) bool {
+	if canEditPost(user, post) {
+		return true
+	}
+	return user != nil && !user.Disabled && post.UserID == user.ID
+}
+
+// canSetPostStatus 判断用户能否将文章设为指定状态，没有发布权限的用户只能保存草稿
+func canSetPostStatus(user *models.User, status string) bool {
+	if status == models.PostStatusDraft {
+		return true
+	}
+	return user != nil && user.Can(models.PermPublishPost)
+}
@@ -1,21 +1,79 @@
 package controllers
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"gin-blog/backend/database"
 	"gin-blog/backend/models"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// findOrCreateTags 按名称查找标签，不存在时创建；空白名称会被忽略。
+// 名称比较不区分大小写，已存在的标签沿用其原有写法，避免产生 "Go"、"go" 这样的重复标签。
+func findOrCreateTags(db *gorm.DB, names []string) ([]*models.Tag, error) {
+	var tags []*models.Tag
+	seen := make(map[string]bool, len(names))
+	for _, tagName := range names {
+		normalized := normalizeTagName(tagName)
+		if normalized == "" || seen[strings.ToLower(normalized)] {
+			continue
+		}
+		seen[strings.ToLower(normalized)] = true
+
+		var tag models.Tag
+		err := db.Unscoped().Where("LOWER(name) = LOWER(?)", normalized).First(&tag).Error
+		if err == nil && tag.DeletedAt.Valid {
+			// 名称唯一约束对已软删除的标签同样生效，直接恢复旧标签
+			err = db.Unscoped().Model(&tag).Update("deleted_at", nil).Error
+		}
+		if err == gorm.ErrRecordNotFound {
+			slug, slugErr := uniqueSlug(db, &models.Tag{}, models.SlugKindTag, normalized, 0)
+			if slugErr != nil {
+				return nil, slugErr
+			}
+			tag = models.Tag{Name: normalized, Slug: slug}
+			err = db.Create(&tag).Error
+		}
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, &tag)
+	}
+	return tags, nil
+}
+
 type CreatePostInput struct {
-	Title      string   `json:"title" binding:"required"`
-	Content    string   `json:"content" binding:"required"`
-	Tags       []string `json:"tags"`
-	CategoryID *uint    `json:"category_id,omitempty"`
+	Title      string     `json:"title" binding:"required"`
+	Slug       string     `json:"slug,omitempty"` // 留空时根据标题生成
+	Content    string     `json:"content" binding:"required"`
+	Tags       []string   `json:"tags"`
+	CategoryID *uint      `json:"category_id,omitempty"`
+	Status     string     `json:"status,omitempty"`
+	PublishAt  *time.Time `json:"publish_at,omitempty"`
+}
+
+// resolvePostStatus 校验文章状态与定时发布时间的组合。
+// 未指定状态时，带有未来发布时间的文章视为定时发布，否则直接发布。
+func resolvePostStatus(status string, publishAt *time.Time, now time.Time) (string, error) {
+	if status == "" {
+		if publishAt != nil && publishAt.After(now) {
+			return models.PostStatusScheduled, nil
+		}
+		return models.PostStatusPublished, nil
+	}
+	if !models.IsValidPostStatus(status) {
+		return "", fmt.Errorf("无效的文章状态: %s", status)
+	}
+	if status == models.PostStatusScheduled && (publishAt == nil || !publishAt.After(now)) {
+		return "", fmt.Errorf("定时发布的文章必须指定未来的发布时间")
+	}
+	return status, nil
 }
 
 func CreatePost(c *gin.Context) {
@@ -31,21 +89,27 @@ func CreatePost(c *gin.Context) {
 		return
 	}
 
-	var tagsToAssociate []*models.Tag
-	if len(input.Tags) > 0 {
-		for _, tagName := range input.Tags {
-			trimmedTagName := strings.TrimSpace(tagName)
-			if trimmedTagName == "" {
-				continue
-			}
-			var tag models.Tag
-			err := database.DB.Where("name = ?", trimmedTagName).FirstOrCreate(&tag, models.Tag{Name: trimmedTagName}).Error
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "处理标签失败: " + err.Error()})
-				return
-			}
-			tagsToAssociate = append(tagsToAssociate, &tag)
-		}
+	tagsToAssociate, err := findOrCreateTags(database.DB, input.Tags)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "处理标签失败: " + err.Error()})
+		return
+	}
+
+	user := currentUser(c)
+	if input.Status == "" && !canSetPostStatus(user, models.PostStatusPublished) {
+		// 投稿者未指定状态时默认保存为草稿
+		input.Status = models.PostStatusDraft
+	}
+
+	now := time.Now()
+	status, err := resolvePostStatus(input.Status, input.PublishAt, now)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !canSetPostStatus(user, status) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "您只能提交草稿，发布需由编辑审核"})
+		return
 	}
 
 	post := models.Post{
@@ -53,6 +117,25 @@ func CreatePost(c *gin.Context) {
 		Content:    input.Content,
 		UserID:     userID.(uint),
 		CategoryID: input.CategoryID,
+		Status:     status,
+	}
+	if status == models.PostStatusScheduled {
+		post.PublishAt = input.PublishAt
+	}
+	if status == models.PostStatusPublished {
+		post.PublishedAt = &now
+	}
+	if err := renderPostContent(&post); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "渲染文章内容失败"})
+		return
+	}
+	slugSource := input.Slug
+	if strings.TrimSpace(slugSource) == "" {
+		slugSource = input.Title
+	}
+	if _, err := setPostSlug(database.DB, &post, slugSource); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成文章 slug 失败"})
+		return
 	}
 
 	tx := database.DB.Begin()
@@ -75,6 +158,18 @@ func CreatePost(c *gin.Context) {
 		}
 	}
 
+	if err := database.IndexPost(tx, &post); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新搜索索引失败"})
+		return
+	}
+
+	if _, err := savePostRevision(tx, post.ID, post.UserID, nil); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存文章版本失败"})
+		return
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "提交事务失败"})
 		return
@@ -89,51 +184,72 @@ func CreatePost(c *gin.Context) {
 }
 
 func GetPosts(c *gin.Context) {
-	var posts []models.Post
-	if err := database.DB.Preload("User").Preload("Tags").Preload("Category").Order("created_at desc").Find(&posts).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文章列表失败"})
+	var q PostListQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的查询参数: " + err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, posts)
+	listPosts(c, q)
 }
 
-func GetPostsByTag(c *gin.Context) {
-	tagName := c.Param("tagName")
+// findTagByName 按 slug 或名称查找标签，也接受标签改名前的旧 slug，失败时直接写入错误响应
+func findTagByName(c *gin.Context, tagName string) (*models.Tag, bool) {
 	if tagName == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "标签名不能为空"})
-		return
+		return nil, false
 	}
 
 	var tag models.Tag
-	if err := database.DB.Where("name = ?", tagName).First(&tag).Error; err != nil {
+	err := database.DB.Where("slug = ?", tagName).First(&tag).Error
+	if err == gorm.ErrRecordNotFound {
+		err = database.DB.Where("name = ?", tagName).First(&tag).Error
+	}
+	if err == gorm.ErrRecordNotFound {
+		var redirect models.SlugRedirect
+		if err = database.DB.Where("kind = ? AND old_slug = ?", models.SlugKindTag, tagName).First(&redirect).Error; err == nil {
+			err = database.DB.First(&tag, redirect.TargetID).Error
+		}
+	}
+	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "标签未找到"})
-			return
+			return nil, false
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "查找标签失败"})
-		return
+		return nil, false
 	}
+	return &tag, true
+}
 
-	var posts []models.Post
-	err := database.DB.Model(&tag).Preload("User").Preload("Tags").Preload("Category").Order("created_at desc").Association("Posts").Find(&posts)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "根据标签获取文章失败"})
+func GetPostsByTag(c *gin.Context) {
+	tag, ok := findTagByName(c, c.Param("tagName"))
+	if !ok {
 		return
 	}
 
-	c.JSON(http.StatusOK, posts)
+	var q PostListQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的查询参数: " + err.Error()})
+		return
+	}
+	q.Tags = []string{tag.Name}
+	listPosts(c, q)
 }
 
+// GetPost 按 ID 或 slug 获取文章，旧 slug 会永久重定向到当前地址
 func GetPost(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := strconv.ParseUint(idStr, 10, 32)
+	id, redirected, err := resolveSlugID(&models.Post{}, models.SlugKindPost, c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文章ID格式"})
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "文章未找到"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文章详情失败"})
 		return
 	}
 
 	var post models.Post
-	if err := database.DB.Preload("User").Preload("Tags").Preload("Category").First(&post, uint(id)).Error; err != nil {
+	if err := database.DB.Preload("User").Preload("Tags").Preload("Category").First(&post, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "文章未找到"})
 			return
@@ -141,15 +257,37 @@ func GetPost(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文章详情失败"})
 		return
 	}
-	c.JSON(http.StatusOK, post)
+	if !post.IsPublic(time.Now()) && !canViewPost(currentUser(c), &post) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "文章未找到"})
+		return
+	}
+	if redirected {
+		c.Redirect(http.StatusMovedPermanently, "/api/posts/"+post.Slug)
+		return
+	}
+
+	if !isAdminRequest(c) {
+		views.Record(post.ID, readerKey(c), time.Now())
+	}
+	post.ViewsCount += views.Pending(post.ID)
+
+	posts := []models.Post{post}
+	if err := markLikedByMe(c, posts); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取点赞状态失败"})
+		return
+	}
+	c.JSON(http.StatusOK, posts[0])
 }
 
 type UpdatePostInput struct {
-	Title       *string  `json:"title,omitempty"`
-	Content     *string  `json:"content,omitempty"`
-	Tags        []string `json:"tags,omitempty"`
-	CategoryID  *uint    `json:"category_id,omitempty"`
-	SetCategory *bool    `json:"set_category,omitempty"`
+	Title       *string    `json:"title,omitempty"`
+	Slug        *string    `json:"slug,omitempty"` // 未指定时标题变化会重新生成 slug，旧 slug 保留跳转
+	Content     *string    `json:"content,omitempty"`
+	Tags        []string   `json:"tags,omitempty"`
+	CategoryID  *uint      `json:"category_id,omitempty"`
+	SetCategory *bool      `json:"set_category,omitempty"`
+	Status      *string    `json:"status,omitempty"`
+	PublishAt   *time.Time `json:"publish_at,omitempty"`
 }
 
 func UpdatePost(c *gin.Context) {
@@ -171,7 +309,8 @@ func UpdatePost(c *gin.Context) {
 	}
 
 	userID, _ := c.Get("userID")
-	if post.UserID != userID.(uint) {
+	user := currentUser(c)
+	if !canEditPost(user, &post) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "您无权修改此文章"})
 		return
 	}
@@ -182,18 +321,93 @@ func UpdatePost(c *gin.Context) {
 		return
 	}
 
+	updateMap := make(map[string]interface{})
+	if input.Status != nil || input.PublishAt != nil {
+		now := time.Now()
+		// 只修改发布时间时，与创建文章一样根据发布时间推断状态
+		status := ""
+		if input.Status != nil {
+			status = *input.Status
+		}
+		publishAt := post.PublishAt
+		if input.PublishAt != nil {
+			publishAt = input.PublishAt
+		}
+		resolved, err := resolvePostStatus(status, publishAt, now)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if !canSetPostStatus(user, resolved) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "您只能提交草稿，发布需由编辑审核"})
+			return
+		}
+		updateMap["status"] = resolved
+		if resolved == models.PostStatusScheduled {
+			updateMap["publish_at"] = publishAt
+		} else {
+			updateMap["publish_at"] = nil
+		}
+		if resolved == models.PostStatusPublished && post.PublishedAt == nil {
+			updateMap["published_at"] = now
+		}
+	}
+
+	// 标签需在事务外查找或创建，SQLite 下事务持有写锁时再用其他连接写入会被阻塞
+	var tagsToUpdate []*models.Tag
+	if input.Tags != nil {
+		tagsToUpdate, err = findOrCreateTags(database.DB, input.Tags)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新时处理标签失败: " + err.Error()})
+			return
+		}
+	}
+
 	tx := database.DB.Begin()
 	if tx.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "开启更新事务失败"})
 		return
 	}
 
-	updateMap := make(map[string]interface{})
+	if err := ensureInitialRevision(tx, &post); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存文章版本失败"})
+		return
+	}
+
+	slugSource := ""
+	if input.Slug != nil && strings.TrimSpace(*input.Slug) != "" {
+		slugSource = *input.Slug
+	} else if input.Title != nil && *input.Title != post.Title {
+		slugSource = *input.Title
+	}
+	if slugSource != "" {
+		changed, err := setPostSlug(tx, &post, slugSource)
+		if err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "生成文章 slug 失败"})
+			return
+		}
+		if changed {
+			updateMap["slug"] = post.Slug
+		}
+	}
+
 	if input.Title != nil {
 		updateMap["title"] = *input.Title
+		post.Title = *input.Title
 	}
 	if input.Content != nil {
 		updateMap["content"] = *input.Content
+		post.Content = *input.Content
+		if err := renderPostContent(&post); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "渲染文章内容失败"})
+			return
+		}
+		for column, value := range renderedPostColumns(&post) {
+			updateMap[column] = value
+		}
 	}
 	if input.SetCategory != nil && *input.SetCategory {
 		updateMap["category_id"] = input.CategoryID
@@ -208,23 +422,6 @@ func UpdatePost(c *gin.Context) {
 	}
 
 	if input.Tags != nil {
-		var tagsToUpdate []*models.Tag
-		if len(input.Tags) > 0 {
-			for _, tagName := range input.Tags {
-				trimmedTagName := strings.TrimSpace(tagName)
-				if trimmedTagName == "" {
-					continue
-				}
-				var tag models.Tag
-				err := database.DB.Where("name = ?", trimmedTagName).FirstOrCreate(&tag, models.Tag{Name: trimmedTagName}).Error
-				if err != nil {
-					tx.Rollback()
-					c.JSON(http.StatusInternalServerError, gin.H{"error": "更新时处理标签失败: " + err.Error()})
-					return
-				}
-				tagsToUpdate = append(tagsToUpdate, &tag)
-			}
-		}
 		if err := tx.Model(&post).Association("Tags").Replace(tagsToUpdate); err != nil {
 			tx.Rollback()
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新文章标签关联失败"})
@@ -232,6 +429,20 @@ func UpdatePost(c *gin.Context) {
 		}
 	}
 
+	if input.Title != nil || input.Content != nil {
+		if err := database.IndexPost(tx, &post); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新搜索索引失败"})
+			return
+		}
+	}
+
+	if _, err := savePostRevision(tx, post.ID, userID.(uint), nil); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存文章版本失败"})
+		return
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "提交更新事务失败"})
 		return
@@ -263,8 +474,7 @@ func DeletePost(c *gin.Context) {
 		return
 	}
 
-	userID, _ := c.Get("userID")
-	if post.UserID != userID.(uint) {
+	if !canEditPost(currentUser(c), &post) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "您无权删除此文章"})
 		return
 	}
@@ -287,6 +497,12 @@ func DeletePost(c *gin.Context) {
 		return
 	}
 
+	if err := database.RemovePostFromIndex(tx, post.ID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除搜索索引失败"})
+		return
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "提交删除事务失败"})
 		return
@@ -295,17 +511,25 @@ func DeletePost(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "文章删除成功"})
 }
 
-// LikePost 增加文章点赞数
+// LikePost 为当前读者点赞文章，重复点赞不会重复计数
 func LikePost(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := strconv.ParseUint(idStr, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文章ID格式"})
+	togglePostLike(c, true)
+}
+
+// UnlikePost 取消当前读者对文章的点赞，未点赞时不影响计数
+func UnlikePost(c *gin.Context) {
+	togglePostLike(c, false)
+}
+
+// togglePostLike 在同一事务中写入或删除点赞记录，并根据 post_likes 重新统计点赞数
+func togglePostLike(c *gin.Context, like bool) {
+	id, ok := resolvePostParam(c)
+	if !ok {
 		return
 	}
 
 	var post models.Post
-	if err := database.DB.First(&post, uint(id)).Error; err != nil {
+	if err := database.DB.First(&post, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "文章未找到"})
 			return
@@ -313,9 +537,27 @@ func LikePost(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "查找文章失败"})
 		return
 	}
+	if !post.IsPublic(time.Now()) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "文章未找到"})
+		return
+	}
 
-	// 增加点赞数
-	if err := database.DB.Model(&post).UpdateColumn("likes_count", gorm.Expr("likes_count + ?", 1)).Error; err != nil {
+	key := readerKey(c)
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if like {
+			postLike := models.PostLike{PostID: post.ID, ReaderKey: key, GuestUserID: readerGuestUserID(c)}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&postLike).Error; err != nil {
+				return err
+			}
+		} else {
+			if err := tx.Where("post_id = ? AND reader_key = ?", post.ID, key).Delete(&models.PostLike{}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&post).UpdateColumn("likes_count",
+			tx.Model(&models.PostLike{}).Select("COUNT(*)").Where("post_id = ?", post.ID)).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新点赞数失败"})
 		return
 	}
@@ -325,71 +567,67 @@ func LikePost(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取更新后的文章数据失败"})
 		return
 	}
+	post.LikedByMe = like
 
+	message := "点赞成功"
+	if !like {
+		message = "取消点赞成功"
+	}
 	c.JSON(http.StatusOK, gin.H{
-		"message": "点赞成功",
+		"message": message,
+		"liked":   like,
 		"post":    post,
 	})
 }
 
-// UnlikePost 减少文章点赞数
-func UnlikePost(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := strconv.ParseUint(idStr, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文章ID格式"})
+// GetBlogStats 获取博客统计信息
+func GetBlogStats(c *gin.Context) {
+	var totalPosts, totalLikes, totalViews, totalComments, totalTags, totalCategories, totalGuestUsers int64
+
+	// 文章数、点赞数和浏览量都只统计公开的文章
+	if err := publicPostScope(database.DB.Model(&models.Post{})).Count(&totalPosts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文章总数失败"})
 		return
 	}
 
-	var post models.Post
-	if err := database.DB.First(&post, uint(id)).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "文章未找到"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "查找文章失败"})
+	if err := publicPostScope(database.DB.Model(&models.Post{})).Select("COALESCE(SUM(likes_count), 0)").Scan(&totalLikes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取总点赞数失败"})
 		return
 	}
 
-	// 减少点赞数，但不能小于0
-	if err := database.DB.Model(&post).Where("likes_count > 0").UpdateColumn("likes_count", gorm.Expr("likes_count - ?", 1)).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新点赞数失败"})
+	if err := publicPostScope(database.DB.Model(&models.Post{})).Select("COALESCE(SUM(views_count), 0)").Scan(&totalViews).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取总浏览量失败"})
 		return
 	}
+	totalViews += views.PendingTotal()
 
-	// 获取更新后的文章数据
-	if err := database.DB.Preload("User").Preload("Tags").Preload("Category").First(&post, uint(id)).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取更新后的文章数据失败"})
+	if err := database.DB.Model(&models.Comment{}).Where("status = ?", models.CommentStatusApproved).Count(&totalComments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取评论总数失败"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "取消点赞成功",
-		"post":    post,
-	})
-}
-
-// GetBlogStats 获取博客统计信息
-func GetBlogStats(c *gin.Context) {
-	var totalPosts int64
-	var totalLikes int64
+	if err := database.DB.Model(&models.Tag{}).Count(&totalTags).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取标签总数失败"})
+		return
+	}
 
-	// 获取文章总数
-	if err := database.DB.Model(&models.Post{}).Count(&totalPosts).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文章总数失败"})
+	if err := database.DB.Model(&models.Category{}).Count(&totalCategories).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取分类总数失败"})
 		return
 	}
 
-	// 获取总点赞数
-	if err := database.DB.Model(&models.Post{}).Select("COALESCE(SUM(likes_count), 0)").Scan(&totalLikes).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取总点赞数失败"})
+	if err := database.DB.Model(&models.GuestUser{}).Count(&totalGuestUsers).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取访客用户总数失败"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"total_posts": totalPosts,
-		"total_likes": totalLikes,
-		"total_views": 12580, // 这里可以后续实现真实的浏览量统计
-		"total_comments": 348, // 这里可以后续实现真实的评论统计
+		"total_posts":       totalPosts,
+		"total_likes":       totalLikes,
+		"total_views":       totalViews,
+		"total_comments":    totalComments,
+		"total_tags":        totalTags,
+		"total_categories":  totalCategories,
+		"total_guest_users": totalGuestUsers,
 	})
-}
\ No newline at end of file
+}
@@ -0,0 +1,401 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gin-blog/backend/database"
+	"gin-blog/backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPostPageSize = 10
+	maxPostPageSize     = 100
+)
+
+// postSortColumns 允许排序的字段及其对应的数据库列
+var postSortColumns = map[string]string{
+	"created_at":  "posts.created_at",
+	"likes_count": "posts.likes_count",
+	"title":       "posts.title",
+}
+
+// PostListQuery 文章列表的分页、筛选和排序参数
+type PostListQuery struct {
+	Page       int      `form:"page"`
+	PageSize   int      `form:"page_size"`
+	Cursor     string   `form:"cursor"`
+	CategoryID *uint    `form:"category_id"`
+	Category   string   `form:"category"` // 分类 slug 或名称
+	Tags       []string `form:"tag"`
+	Author     string   `form:"author"`
+	From       string   `form:"from"`
+	To         string   `form:"to"`
+	Sort       string   `form:"sort"`
+	Order      string   `form:"order"`
+	Status     string   `form:"status"` // 仅后台用户可用，且只能筛选自己可见的文章
+}
+
+// PostListResponse 文章列表的响应结构
+type PostListResponse struct {
+	Data       []models.Post `json:"data"`
+	Total      int64         `json:"total"`
+	Page       int           `json:"page,omitempty"`
+	PageSize   int           `json:"page_size"`
+	TotalPages int           `json:"total_pages"`
+	Next       *string       `json:"next"`
+	Prev       *string       `json:"prev"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+	PrevCursor string        `json:"prev_cursor,omitempty"`
+}
+
+// postCursor 游标分页时记录上一页边界的排序值和ID
+type postCursor struct {
+	Value     interface{} `json:"v"`
+	ID        uint        `json:"id"`
+	Direction string      `json:"d"`
+}
+
+func encodePostCursor(cur postCursor) string {
+	raw, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodePostCursor(s string) (*postCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var cur postCursor
+	if err := json.Unmarshal(raw, &cur); err != nil {
+		return nil, err
+	}
+	if cur.Direction != "next" && cur.Direction != "prev" {
+		return nil, fmt.Errorf("invalid cursor direction")
+	}
+	return &cur, nil
+}
+
+// normalize 校验并填充默认值
+func (q *PostListQuery) normalize() error {
+	if q.Page <= 0 {
+		q.Page = 1
+	}
+	if q.PageSize <= 0 {
+		q.PageSize = defaultPostPageSize
+	}
+	if q.PageSize > maxPostPageSize {
+		q.PageSize = maxPostPageSize
+	}
+
+	if q.Sort == "" {
+		q.Sort = "created_at"
+	}
+	if _, ok := postSortColumns[q.Sort]; !ok {
+		return fmt.Errorf("不支持的排序字段: %s", q.Sort)
+	}
+
+	q.Order = strings.ToLower(q.Order)
+	if q.Order == "" {
+		if q.Sort == "title" {
+			q.Order = "asc"
+		} else {
+			q.Order = "desc"
+		}
+	}
+	if q.Order != "asc" && q.Order != "desc" {
+		return fmt.Errorf("排序方向只能是 asc 或 desc")
+	}
+
+	// 同时支持 tag=a&tag=b 与 tag=a,b 两种写法
+	var tags []string
+	for _, t := range q.Tags {
+		for _, name := range strings.Split(t, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				tags = append(tags, name)
+			}
+		}
+	}
+	q.Tags = tags
+
+	if q.Status != "" && !models.IsValidPostStatus(q.Status) {
+		return fmt.Errorf("无效的文章状态: %s", q.Status)
+	}
+	return nil
+}
+
+// isAdminRequest 判断当前请求是否来自已登录的管理员
+func isAdminRequest(c *gin.Context) bool {
+	return c.GetString("userType") == "admin"
+}
+
+// publicPostScope 仅保留对公众可见的文章：已发布，或定时发布时间已到但调度器尚未处理的文章
+func publicPostScope(db *gorm.DB) *gorm.DB {
+	return db.Where("(posts.status = ? OR (posts.status = ? AND posts.publish_at <= ?))",
+		models.PostStatusPublished, models.PostStatusScheduled, time.Now())
+}
+
+// visiblePostScope 根据请求者身份返回文章可见范围：可以修改任何文章的用户能看到所有状态的文章，
+// 其他后台用户在公开文章之外只能看到自己的文章
+func visiblePostScope(c *gin.Context) func(*gorm.DB) *gorm.DB {
+	user := currentUser(c)
+	if user == nil || user.Disabled {
+		return publicPostScope
+	}
+	if user.Can(models.PermEditAnyPost) {
+		return nil
+	}
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("(posts.status = ? OR (posts.status = ? AND posts.publish_at <= ?) OR posts.user_id = ?)",
+			models.PostStatusPublished, models.PostStatusScheduled, time.Now(), user.ID)
+	}
+}
+
+// parseDateParam 解析日期参数，支持 2006-01-02 与 RFC3339 两种格式
+func parseDateParam(value string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// applyPostFilters 将筛选条件应用到文章查询上
+func applyPostFilters(db *gorm.DB, q *PostListQuery) (*gorm.DB, error) {
+	if q.CategoryID != nil {
+		db = db.Where("posts.category_id = ?", *q.CategoryID)
+	}
+	if q.Category != "" {
+		db = db.Where("posts.category_id IN (?)", database.DB.Model(&models.Category{}).
+			Select("id").
+			Where("slug = ? OR name = ?", q.Category, q.Category))
+	}
+
+	if len(q.Tags) > 0 {
+		db = db.Where("posts.id IN (?)", database.DB.Table("post_tags").
+			Select("post_tags.post_id").
+			Joins("JOIN tags ON tags.id = post_tags.tag_id").
+			Where("(tags.name IN ? OR tags.slug IN ?) AND tags.deleted_at IS NULL", q.Tags, q.Tags))
+	}
+
+	if q.Author != "" {
+		if authorID, err := strconv.ParseUint(q.Author, 10, 32); err == nil {
+			db = db.Where("posts.user_id = ?", uint(authorID))
+		} else {
+			db = db.Where("posts.user_id IN (?)", database.DB.Model(&models.User{}).
+				Select("id").
+				Where("username = ?", q.Author))
+		}
+	}
+
+	if q.From != "" {
+		from, err := parseDateParam(q.From, false)
+		if err != nil {
+			return nil, fmt.Errorf("无效的起始日期: %s", q.From)
+		}
+		db = db.Where("posts.created_at >= ?", from)
+	}
+	if q.To != "" {
+		to, err := parseDateParam(q.To, true)
+		if err != nil {
+			return nil, fmt.Errorf("无效的结束日期: %s", q.To)
+		}
+		db = db.Where("posts.created_at < ?", to)
+	}
+
+	return db, nil
+}
+
+// cursorValue 从游标中还原与排序字段类型一致的比较值
+func cursorValue(sort string, v interface{}) (interface{}, error) {
+	switch sort {
+	case "created_at":
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid cursor value")
+		}
+		return time.Parse(time.RFC3339Nano, s)
+	case "likes_count":
+		f, ok := v.(float64)
+		if !ok {
+			return nil, fmt.Errorf("invalid cursor value")
+		}
+		return int64(f), nil
+	default:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid cursor value")
+		}
+		return s, nil
+	}
+}
+
+func postSortValue(post *models.Post, sort string) interface{} {
+	switch sort {
+	case "created_at":
+		return post.CreatedAt.Format(time.RFC3339Nano)
+	case "likes_count":
+		return post.LikesCount
+	default:
+		return post.Title
+	}
+}
+
+// pageLink 基于当前请求地址生成替换了分页参数的链接
+func pageLink(c *gin.Context, set map[string]string, drop ...string) *string {
+	u := *c.Request.URL
+	values := u.Query()
+	for _, key := range drop {
+		values.Del(key)
+	}
+	for key, value := range set {
+		values.Set(key, value)
+	}
+	u.RawQuery = values.Encode()
+	link := (&url.URL{Path: u.Path, RawQuery: u.RawQuery}).String()
+	return &link
+}
+
+// listPosts 是 GetPosts 与 GetPostsByTag 共用的查询构建器
+func listPosts(c *gin.Context, q PostListQuery) {
+	if err := q.normalize(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filtered, err := applyPostFilters(database.DB.Model(&models.Post{}), &q)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if scope := visiblePostScope(c); scope != nil {
+		filtered = scope(filtered)
+	}
+	if q.Status != "" && isAdminRequest(c) {
+		filtered = filtered.Where("posts.status = ?", q.Status)
+	}
+
+	var total int64
+	if err := filtered.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "统计文章数量失败"})
+		return
+	}
+
+	column := postSortColumns[q.Sort]
+	order := q.Order
+	query := filtered.Session(&gorm.Session{}).Preload("User").Preload("Tags").Preload("Category")
+
+	var cur *postCursor
+	if q.Cursor != "" {
+		cur, err = decodePostCursor(q.Cursor)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的分页游标"})
+			return
+		}
+		value, err := cursorValue(q.Sort, cur.Value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的分页游标"})
+			return
+		}
+
+		// 向前翻页时反转比较方向和排序，取到结果后再倒序还原
+		cmp := "<"
+		if (order == "asc") == (cur.Direction == "next") {
+			cmp = ">"
+		}
+		if cur.Direction == "prev" {
+			if order == "asc" {
+				order = "desc"
+			} else {
+				order = "asc"
+			}
+		}
+		query = query.Where(
+			fmt.Sprintf("((%s %s ?) OR (%s = ? AND posts.id %s ?))", column, cmp, column, cmp),
+			value, value, cur.ID,
+		)
+	} else {
+		query = query.Offset((q.Page - 1) * q.PageSize)
+	}
+
+	// 多取一条用于判断是否还有下一页
+	var posts []models.Post
+	err = query.Order(fmt.Sprintf("%s %s, posts.id %s", column, order, order)).
+		Limit(q.PageSize + 1).
+		Find(&posts).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文章列表失败"})
+		return
+	}
+
+	if err := markLikedByMe(c, posts); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取点赞状态失败"})
+		return
+	}
+
+	hasMore := len(posts) > q.PageSize
+	if hasMore {
+		posts = posts[:q.PageSize]
+	}
+	if cur != nil && cur.Direction == "prev" {
+		for i, j := 0, len(posts)-1; i < j; i, j = i+1, j-1 {
+			posts[i], posts[j] = posts[j], posts[i]
+		}
+	}
+
+	resp := PostListResponse{
+		Data:       posts,
+		Total:      total,
+		PageSize:   q.PageSize,
+		TotalPages: int(math.Ceil(float64(total) / float64(q.PageSize))),
+	}
+	if resp.Data == nil {
+		resp.Data = []models.Post{}
+	}
+
+	// 无论哪种分页方式都返回游标，便于客户端从页码分页切换到游标分页
+	if len(posts) > 0 {
+		first, last := &posts[0], &posts[len(posts)-1]
+		if hasMore || (cur != nil && cur.Direction == "prev") {
+			resp.NextCursor = encodePostCursor(postCursor{Value: postSortValue(last, q.Sort), ID: last.ID, Direction: "next"})
+		}
+		if (cur == nil && q.Page > 1) || (cur != nil && (cur.Direction == "next" || hasMore)) {
+			resp.PrevCursor = encodePostCursor(postCursor{Value: postSortValue(first, q.Sort), ID: first.ID, Direction: "prev"})
+		}
+	}
+
+	if cur == nil {
+		resp.Page = q.Page
+		if hasMore {
+			resp.Next = pageLink(c, map[string]string{"page": strconv.Itoa(q.Page + 1)})
+		}
+		if q.Page > 1 {
+			resp.Prev = pageLink(c, map[string]string{"page": strconv.Itoa(q.Page - 1)})
+		}
+	} else {
+		if resp.NextCursor != "" {
+			resp.Next = pageLink(c, map[string]string{"cursor": resp.NextCursor}, "page")
+		}
+		if resp.PrevCursor != "" {
+			resp.Prev = pageLink(c, map[string]string{"cursor": resp.PrevCursor}, "page")
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
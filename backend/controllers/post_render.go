@@ -0,0 +1,53 @@
+package controllers
+
+import (
+	"encoding/json"
+
+	"gin-blog/backend/models"
+	"gin-blog/backend/utils"
+)
+
+// renderPostContent 渲染文章正文，并把 HTML、目录、字数和阅读时长缓存到文章上
+func renderPostContent(post *models.Post) error {
+	rendered, err := utils.RenderMarkdownDocument(post.Content)
+	if err != nil {
+		return err
+	}
+	post.ContentHTML = rendered.HTML
+	post.TOC = buildTOC(rendered.Headings)
+	post.WordCount = rendered.WordCount
+	post.ReadingTime = rendered.ReadingTimeMinutes()
+	return nil
+}
+
+// renderedPostColumns 返回渲染结果对应的待更新字段。
+// 以 map 更新时 GORM 不会调用字段的 serializer，因此目录需要预先序列化。
+func renderedPostColumns(post *models.Post) map[string]interface{} {
+	toc, _ := json.Marshal(post.TOC)
+	return map[string]interface{}{
+		"content_html": post.ContentHTML,
+		"toc":          string(toc),
+		"word_count":   post.WordCount,
+		"reading_time": post.ReadingTime,
+	}
+}
+
+// buildTOC 将平铺的标题列表按层级组装为嵌套目录
+func buildTOC(headings []utils.Heading) []models.TOCEntry {
+	var build func(i int, parentLevel int) ([]models.TOCEntry, int)
+	build = func(i int, parentLevel int) ([]models.TOCEntry, int) {
+		entries := []models.TOCEntry{}
+		for i < len(headings) && headings[i].Level > parentLevel {
+			h := headings[i]
+			entry := models.TOCEntry{Level: h.Level, ID: h.ID, Text: h.Text}
+			entry.Children, i = build(i+1, h.Level)
+			if len(entry.Children) == 0 {
+				entry.Children = nil
+			}
+			entries = append(entries, entry)
+		}
+		return entries, i
+	}
+	toc, _ := build(0, 0)
+	return toc
+}
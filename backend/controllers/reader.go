@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"gin-blog/backend/database"
+	"gin-blog/backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readerKey 返回当前读者的唯一标识：登录用户使用其ID，匿名访客使用 IP 和 User-Agent 的 HMAC。
+// 不使用客户端可以随意更换的请求头，HMAC 使用服务端密钥，无法根据 IP 反推出标识。
+func readerKey(c *gin.Context) string {
+	if c.GetString("userType") == "guest" {
+		if id, ok := c.Get("guestUserID"); ok {
+			return fmt.Sprintf("guest:%d", id.(uint))
+		}
+	}
+	if c.GetString("userType") == "admin" {
+		if id, ok := c.Get("userID"); ok {
+			return fmt.Sprintf("user:%d", id.(uint))
+		}
+	}
+
+	mac := hmac.New(sha256.New, []byte("reader-key|"+appConfig.Auth.JWTSecret))
+	mac.Write([]byte(c.ClientIP() + "|" + c.Request.UserAgent()))
+	return "anon:" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// readerGuestUserID 返回当前 GitHub 访客的ID，非访客时返回 nil
+func readerGuestUserID(c *gin.Context) *uint {
+	if c.GetString("userType") != "guest" {
+		return nil
+	}
+	id, ok := c.Get("guestUserID")
+	if !ok {
+		return nil
+	}
+	guestUserID := id.(uint)
+	return &guestUserID
+}
+
+// markLikedByMe 为文章列表填充当前读者的点赞状态
+func markLikedByMe(c *gin.Context, posts []models.Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(posts))
+	for i := range posts {
+		ids[i] = posts[i].ID
+	}
+
+	var likedIDs []uint
+	if err := database.DB.Model(&models.PostLike{}).
+		Where("reader_key = ? AND post_id IN ?", readerKey(c), ids).
+		Pluck("post_id", &likedIDs).Error; err != nil {
+		return err
+	}
+
+	liked := make(map[uint]bool, len(likedIDs))
+	for _, id := range likedIDs {
+		liked[id] = true
+	}
+	for i := range posts {
+		posts[i].LikedByMe = liked[posts[i].ID]
+	}
+	return nil
+}
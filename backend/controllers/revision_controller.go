@@ -0,0 +1,306 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gin-blog/backend/database"
+	"gin-blog/backend/models"
+	"gin-blog/backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const revisionDiffContext = 3
+
+// savePostRevision 为文章当前状态保存一份快照，必须在写入文章的同一事务中调用。
+// 先锁定文章行，同一文章的并发更新依次分配版本号；最新版本号也用加锁读取，
+// MySQL 的可重复读下普通查询可能读到事务开始时的旧快照。SQLite 不支持行锁，写事务本身是串行的。
+func savePostRevision(tx *gorm.DB, postID uint, editorID uint, restoredFromID *uint) (*models.PostRevision, error) {
+	var post models.Post
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&post, postID).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&post).Association("Tags").Find(&post.Tags); err != nil {
+		return nil, err
+	}
+
+	// 已软删除的版本仍占用版本号
+	var latest []models.PostRevision
+	if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "version").
+		Where("post_id = ?", postID).Order("version desc").Limit(1).Find(&latest).Error; err != nil {
+		return nil, err
+	}
+	version := 1
+	if len(latest) > 0 {
+		version = latest[0].Version + 1
+	}
+
+	tagNames := make([]string, 0, len(post.Tags))
+	for _, tag := range post.Tags {
+		tagNames = append(tagNames, tag.Name)
+	}
+
+	revision := models.PostRevision{
+		PostID:         post.ID,
+		Version:        version,
+		Title:          post.Title,
+		Content:        post.Content,
+		Tags:           tagNames,
+		CategoryID:     post.CategoryID,
+		EditorID:       editorID,
+		RestoredFromID: restoredFromID,
+	}
+	if err := tx.Create(&revision).Error; err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// ensureInitialRevision 为尚无历史记录的旧文章补存更新前的快照，避免首次更新时丢失原文
+func ensureInitialRevision(tx *gorm.DB, post *models.Post) error {
+	var count int64
+	if err := tx.Model(&models.PostRevision{}).Where("post_id = ?", post.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	_, err := savePostRevision(tx, post.ID, post.UserID, nil)
+	return err
+}
+
+// revisionDocument 将版本快照拼成便于比较的纯文本
+func revisionDocument(rev *models.PostRevision) string {
+	category := "-"
+	if rev.CategoryID != nil {
+		category = strconv.FormatUint(uint64(*rev.CategoryID), 10)
+	}
+	return fmt.Sprintf("Title: %s\nCategory: %s\nTags: %s\n\n%s",
+		rev.Title, category, strings.Join(rev.Tags, ", "), rev.Content)
+}
+
+func findPostRevision(c *gin.Context, postID uint, param string) (*models.PostRevision, bool) {
+	revisionID, err := strconv.ParseUint(param, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的版本ID格式"})
+		return nil, false
+	}
+
+	var revision models.PostRevision
+	if err := database.DB.Preload("Editor").Where("post_id = ?", postID).First(&revision, uint(revisionID)).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "版本未找到"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取版本失败"})
+		return nil, false
+	}
+	return &revision, true
+}
+
+func parsePostID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文章ID格式"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// authorizeRevisionRead 解析文章ID并确认当前用户可以查看该文章的历史版本
+func authorizeRevisionRead(c *gin.Context) (uint, bool) {
+	postID, ok := parsePostID(c)
+	if !ok {
+		return 0, false
+	}
+
+	var post models.Post
+	if err := database.DB.First(&post, postID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "文章未找到"})
+			return 0, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查找文章失败"})
+		return 0, false
+	}
+	if !canViewPost(currentUser(c), &post) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "您无权查看此文章的历史版本"})
+		return 0, false
+	}
+	return postID, true
+}
+
+// GetPostRevisions 列出文章的所有历史版本，按版本号倒序
+func GetPostRevisions(c *gin.Context) {
+	postID, ok := authorizeRevisionRead(c)
+	if !ok {
+		return
+	}
+
+	var revisions []models.PostRevision
+	if err := database.DB.Preload("Editor").Where("post_id = ?", postID).Order("version desc").Find(&revisions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取版本列表失败"})
+		return
+	}
+	c.JSON(http.StatusOK, revisions)
+}
+
+// GetPostRevision 获取单个历史版本
+func GetPostRevision(c *gin.Context) {
+	postID, ok := authorizeRevisionRead(c)
+	if !ok {
+		return
+	}
+	revision, ok := findPostRevision(c, postID, c.Param("revisionId"))
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, revision)
+}
+
+// DiffPostRevisions 比较同一文章的两个历史版本，返回 unified diff
+func DiffPostRevisions(c *gin.Context) {
+	postID, ok := authorizeRevisionRead(c)
+	if !ok {
+		return
+	}
+	if c.Query("from") == "" || c.Query("to") == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "必须同时指定 from 和 to 版本ID"})
+		return
+	}
+
+	from, ok := findPostRevision(c, postID, c.Query("from"))
+	if !ok {
+		return
+	}
+	to, ok := findPostRevision(c, postID, c.Query("to"))
+	if !ok {
+		return
+	}
+
+	diff := utils.UnifiedDiff(
+		fmt.Sprintf("post-%d@v%d", postID, from.Version),
+		fmt.Sprintf("post-%d@v%d", postID, to.Version),
+		revisionDocument(from),
+		revisionDocument(to),
+		revisionDiffContext,
+	)
+	c.JSON(http.StatusOK, gin.H{
+		"from":      from,
+		"to":        to,
+		"diff":      diff,
+		"identical": diff == "",
+	})
+}
+
+// RestorePostRevision 将文章恢复为指定的历史版本，恢复结果会作为一个新版本记录下来
+func RestorePostRevision(c *gin.Context) {
+	postID, ok := parsePostID(c)
+	if !ok {
+		return
+	}
+
+	var post models.Post
+	if err := database.DB.First(&post, postID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "文章未找到"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查找文章失败"})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	if !canEditPost(currentUser(c), &post) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "您无权修改此文章"})
+		return
+	}
+
+	revision, ok := findPostRevision(c, postID, c.Param("revisionId"))
+	if !ok {
+		return
+	}
+
+	tags, err := findOrCreateTags(database.DB, revision.Tags)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "恢复时处理标签失败: " + err.Error()})
+		return
+	}
+
+	tx := database.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "开启恢复事务失败"})
+		return
+	}
+
+	if err := ensureInitialRevision(tx, &post); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存文章版本失败"})
+		return
+	}
+
+	if revision.Title != post.Title {
+		if _, err := setPostSlug(tx, &post, revision.Title); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "生成文章 slug 失败"})
+			return
+		}
+	}
+	post.Title = revision.Title
+	post.Content = revision.Content
+	if err := renderPostContent(&post); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "渲染文章内容失败"})
+		return
+	}
+	updates := renderedPostColumns(&post)
+	updates["slug"] = post.Slug
+	updates["title"] = revision.Title
+	updates["content"] = revision.Content
+	updates["category_id"] = revision.CategoryID
+	if err := tx.Model(&post).Updates(updates).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "恢复文章内容失败"})
+		return
+	}
+
+	if err := tx.Model(&post).Association("Tags").Replace(tags); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "恢复文章标签失败"})
+		return
+	}
+
+	if err := database.IndexPost(tx, &post); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新搜索索引失败"})
+		return
+	}
+
+	newRevision, err := savePostRevision(tx, post.ID, userID.(uint), &revision.ID)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存文章版本失败"})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "提交恢复事务失败"})
+		return
+	}
+
+	var restoredPost models.Post
+	if err := database.DB.Preload("User").Preload("Tags").Preload("Category").First(&restoredPost, post.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取恢复后的文章详情失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "文章已恢复",
+		"post":     restoredPost,
+		"revision": newRevision,
+	})
+}
@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"math"
+	"net/http"
+	"strings"
+
+	"gin-blog/backend/database"
+	"gin-blog/backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxSearchTaxonomyResults = 10
+
+type SearchQuery struct {
+	Q        string `form:"q"`
+	Page     int    `form:"page"`
+	PageSize int    `form:"page_size"`
+}
+
+// SearchResult 单条检索结果，高亮片段中的命中词以 <mark> 标签包裹，其余内容已做 HTML 转义
+type SearchResult struct {
+	Post           models.Post `json:"post"`
+	Rank           float64     `json:"rank"`
+	TitleHighlight string      `json:"title_highlight"`
+	Snippet        string      `json:"snippet"`
+}
+
+// Search 全文检索文章，并返回名称匹配的标签和分类
+func Search(c *gin.Context) {
+	var q SearchQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的查询参数: " + err.Error()})
+		return
+	}
+	q.Q = strings.TrimSpace(q.Q)
+	if q.Q == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "搜索关键词不能为空"})
+		return
+	}
+	if q.Page <= 0 {
+		q.Page = 1
+	}
+	if q.PageSize <= 0 {
+		q.PageSize = defaultPostPageSize
+	}
+	if q.PageSize > maxPostPageSize {
+		q.PageSize = maxPostPageSize
+	}
+
+	hits, total, err := database.SearchPosts(q.Q, q.PageSize, (q.Page-1)*q.PageSize, visiblePostScope(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "搜索文章失败"})
+		return
+	}
+
+	ids := make([]uint, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.PostID
+	}
+	postsByID := make(map[uint]models.Post, len(hits))
+	if len(ids) > 0 {
+		var posts []models.Post
+		if err := database.DB.Preload("User").Preload("Tags").Preload("Category").Where("id IN ?", ids).Find(&posts).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取搜索结果详情失败"})
+			return
+		}
+		for _, post := range posts {
+			postsByID[post.ID] = post
+		}
+	}
+
+	results := make([]SearchResult, 0, len(hits))
+	for _, hit := range hits {
+		post, ok := postsByID[hit.PostID]
+		if !ok {
+			continue
+		}
+		results = append(results, SearchResult{
+			Post:           post,
+			Rank:           hit.Rank,
+			TitleHighlight: hit.TitleHighlight,
+			Snippet:        hit.Snippet,
+		})
+	}
+
+	pattern := "%" + database.EscapeLike(q.Q) + "%"
+	nameLike := database.LikeCondition(database.DB, "name")
+	var tags []models.Tag
+	if err := database.DB.Where(nameLike, pattern).Order("name asc").Limit(maxSearchTaxonomyResults).Find(&tags).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "搜索标签失败"})
+		return
+	}
+	var categories []models.Category
+	if err := database.DB.Where(nameLike, pattern).Order("name asc").Limit(maxSearchTaxonomyResults).Find(&categories).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "搜索分类失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"query":       q.Q,
+		"results":     results,
+		"total":       total,
+		"page":        q.Page,
+		"page_size":   q.PageSize,
+		"total_pages": int(math.Ceil(float64(total) / float64(q.PageSize))),
+		"tags":        tags,
+		"categories":  categories,
+	})
+}
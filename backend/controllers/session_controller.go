@@ -0,0 +1,363 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"gin-blog/backend/database"
+	"gin-blog/backend/models"
+	"gin-blog/backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var (
+	errTokenRevoked           = errors.New("token has been revoked")
+	errRefreshTokenReused     = errors.New("refresh token has already been used")
+	errSessionSubjectGone     = errors.New("account no longer exists")
+	errSessionSubjectDisabled = errors.New("account has been disabled")
+)
+
+// hashToken 计算一次性令牌（邀请码、刷新令牌）的 SHA-256，数据库中只保存摘要
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func newSecureToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// sessionSubject 登录会话所属的后台用户或访客
+type sessionSubject struct {
+	Type         string
+	ID           uint
+	Username     string
+	AvatarURL    string
+	Role         string
+	Provider     string // 访客本次登录使用的第三方登录提供方，随刷新令牌保留
+	TokenVersion int
+}
+
+func adminSubject(user *models.User) sessionSubject {
+	return sessionSubject{
+		Type:         models.SubjectAdmin,
+		ID:           user.ID,
+		Username:     user.Username,
+		Role:         user.Role,
+		TokenVersion: user.TokenVersion,
+	}
+}
+
+func guestSubject(guest *models.GuestUser, provider string) sessionSubject {
+	return sessionSubject{
+		Type:         models.SubjectGuest,
+		ID:           guest.ID,
+		Username:     guest.Username,
+		AvatarURL:    guest.AvatarURL,
+		Provider:     provider,
+		TokenVersion: guest.TokenVersion,
+	}
+}
+
+// loadSessionSubject 刷新令牌时重新读取主体，确保用户名、角色和 token 版本是最新的
+func loadSessionSubject(subjectType string, id uint, provider string) (sessionSubject, error) {
+	switch subjectType {
+	case models.SubjectAdmin:
+		var user models.User
+		if err := database.DB.First(&user, id).Error; err != nil {
+			return sessionSubject{}, errSessionSubjectGone
+		}
+		if user.Disabled || user.IsPending() {
+			return sessionSubject{}, errSessionSubjectDisabled
+		}
+		return adminSubject(&user), nil
+	case models.SubjectGuest:
+		var guest models.GuestUser
+		if err := database.DB.First(&guest, id).Error; err != nil {
+			return sessionSubject{}, errSessionSubjectGone
+		}
+		return guestSubject(&guest, provider), nil
+	}
+	return sessionSubject{}, errSessionSubjectGone
+}
+
+type sessionTokens struct {
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+// issueSession 签发访问令牌和刷新令牌，familyID 为空时开启一个新的登录会话
+func issueSession(tx *gorm.DB, c *gin.Context, subject sessionSubject, familyID string) (*sessionTokens, *models.RefreshToken, error) {
+	accessToken, accessExpiresAt, err := utils.GenerateToken(subject.ID, subject.Username, subject.AvatarURL, subject.Type, subject.Provider, subject.TokenVersion)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	refreshToken, err := newSecureToken()
+	if err != nil {
+		return nil, nil, err
+	}
+	if familyID == "" {
+		if familyID, err = newSecureToken(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	now := time.Now()
+	// 顺带清理该主体已过期的刷新令牌
+	if err := tx.Where("subject_type = ? AND subject_id = ? AND expires_at < ?", subject.Type, subject.ID, now).
+		Delete(&models.RefreshToken{}).Error; err != nil {
+		return nil, nil, err
+	}
+
+	record := models.RefreshToken{
+		SubjectType: subject.Type,
+		SubjectID:   subject.ID,
+		TokenHash:   hashToken(refreshToken),
+		FamilyID:    familyID,
+		Provider:    subject.Provider,
+		ExpiresAt:   now.Add(appConfig.Auth.RefreshTokenTTL),
+		UserAgent:   c.Request.UserAgent(),
+		IP:          c.ClientIP(),
+	}
+	if err := tx.Create(&record).Error; err != nil {
+		return nil, nil, err
+	}
+
+	return &sessionTokens{
+		AccessToken:      accessToken,
+		AccessExpiresAt:  accessExpiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: record.ExpiresAt,
+	}, &record, nil
+}
+
+// sessionResponse 登录、刷新接口的统一响应，保留原有的 token、用户字段
+func sessionResponse(subject sessionSubject, tokens *sessionTokens) gin.H {
+	response := gin.H{
+		"token":              tokens.AccessToken,
+		"expires_at":         tokens.AccessExpiresAt,
+		"refresh_token":      tokens.RefreshToken,
+		"refresh_expires_at": tokens.RefreshExpiresAt,
+		"username":           subject.Username,
+		"type":               subject.Type,
+	}
+	if subject.Type == models.SubjectAdmin {
+		response["user_id"] = subject.ID
+		response["role"] = subject.Role
+	} else {
+		response["guest_id"] = subject.ID
+		response["avatar_url"] = subject.AvatarURL
+	}
+	return response
+}
+
+// revokeRefreshFamily 吊销同一登录会话轮换出的所有刷新令牌
+func revokeRefreshFamily(tx *gorm.DB, familyID string) error {
+	return tx.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeAllSessions 让主体的所有会话失效：递增 token 版本使已签发的访问令牌失效，并吊销全部刷新令牌
+func RevokeAllSessions(subjectType string, id uint) error {
+	var model interface{}
+	switch subjectType {
+	case models.SubjectAdmin:
+		model = &models.User{}
+	case models.SubjectGuest:
+		model = &models.GuestUser{}
+	default:
+		return errSessionSubjectGone
+	}
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(model).Where("id = ?", id).
+			UpdateColumn("token_version", gorm.Expr("token_version + 1")).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.RefreshToken{}).
+			Where("subject_type = ? AND subject_id = ? AND revoked_at IS NULL", subjectType, id).
+			Update("revoked_at", time.Now()).Error
+	})
+}
+
+// CheckTokenRevocation 检查访问令牌是否已注销（jti 黑名单）或因"退出所有会话"而失效，
+// 由 main 注入到 utils.TokenRevocationCheck，在 ValidateToken 中调用
+func CheckTokenRevocation(claims *utils.Claims) error {
+	var revoked int64
+	if err := database.DB.Model(&models.RevokedToken{}).Where("jti = ?", claims.ID).Count(&revoked).Error; err != nil {
+		return errors.New("could not verify token")
+	}
+	if revoked > 0 {
+		return errTokenRevoked
+	}
+
+	var version int
+	var err error
+	if claims.UserID != 0 {
+		err = database.DB.Model(&models.User{}).Where("id = ?", claims.UserID).Select("token_version").Scan(&version).Error
+	} else if claims.GuestUserID != 0 {
+		err = database.DB.Model(&models.GuestUser{}).Where("id = ?", claims.GuestUserID).Select("token_version").Scan(&version).Error
+	}
+	if err != nil {
+		return errors.New("could not verify token")
+	}
+	if version != claims.TokenVersion {
+		return errTokenRevoked
+	}
+	return nil
+}
+
+type RefreshSessionInput struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// RefreshSession 使用刷新令牌换取新的访问令牌。刷新令牌每次使用后都会轮换，
+// 已轮换的旧令牌再次出现时吊销整个会话。
+func RefreshSession(c *gin.Context) {
+	var input RefreshSessionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var stored models.RefreshToken
+	if err := database.DB.Where("token_hash = ?", hashToken(input.RefreshToken)).First(&stored).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if stored.RevokedAt != nil {
+		if err := revokeRefreshFamily(database.DB, stored.FamilyID); err != nil {
+			log.Printf("Failed to revoke refresh token family %s: %v", stored.FamilyID, err)
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token has been revoked"})
+		return
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token has expired"})
+		return
+	}
+
+	subject, err := loadSessionSubject(stored.SubjectType, stored.SubjectID, stored.Provider)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token: " + err.Error()})
+		return
+	}
+
+	var tokens *sessionTokens
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// 条件更新保证并发请求中只有一个能完成轮换
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", stored.ID).
+			Update("revoked_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errRefreshTokenReused
+		}
+
+		issued, record, err := issueSession(tx, c, subject, stored.FamilyID)
+		if err != nil {
+			return err
+		}
+		tokens = issued
+		return tx.Model(&models.RefreshToken{}).Where("id = ?", stored.ID).Update("replaced_by_id", record.ID).Error
+	})
+	if err != nil {
+		if err == errRefreshTokenReused {
+			if err := revokeRefreshFamily(database.DB, stored.FamilyID); err != nil {
+				log.Printf("Failed to revoke refresh token family %s: %v", stored.FamilyID, err)
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token has been revoked"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, sessionResponse(subject, tokens))
+}
+
+type LogoutInput struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Logout 注销当前会话：吊销刷新令牌所在的会话，并将当前访问令牌加入黑名单直到其过期
+func Logout(c *gin.Context) {
+	var input LogoutInput
+	// 请求体可以为空，此时只注销访问令牌
+	_ = c.ShouldBindJSON(&input)
+
+	if input.RefreshToken != "" {
+		var stored models.RefreshToken
+		err := database.DB.Where("token_hash = ?", hashToken(input.RefreshToken)).First(&stored).Error
+		if err == nil {
+			err = revokeRefreshFamily(database.DB, stored.FamilyID)
+		}
+		if err != nil && err != gorm.ErrRecordNotFound {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke refresh token"})
+			return
+		}
+	}
+
+	if value, ok := c.Get("tokenClaims"); ok {
+		claims := value.(*utils.Claims)
+		now := time.Now()
+		if err := database.DB.Where("expires_at < ?", now).Delete(&models.RevokedToken{}).Error; err != nil {
+			log.Printf("Failed to prune revoked tokens: %v", err)
+		}
+		revoked := models.RevokedToken{JTI: claims.ID, ExpiresAt: claims.ExpiresAt.Time}
+		if err := database.DB.Save(&revoked).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke access token"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+// LogoutAllSessions 当前用户或访客退出所有设备上的会话
+func LogoutAllSessions(c *gin.Context) {
+	subjectType, id := models.SubjectAdmin, c.GetUint("userID")
+	if c.GetString("userType") == models.SubjectGuest {
+		subjectType, id = models.SubjectGuest, c.GetUint("guestUserID")
+	}
+	if err := RevokeAllSessions(subjectType, id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out all sessions"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "All sessions have been logged out"})
+}
+
+// LogoutUserSessions 管理员强制指定后台用户退出所有会话
+func LogoutUserSessions(c *gin.Context) {
+	user, ok := findUser(c)
+	if !ok {
+		return
+	}
+	if err := RevokeAllSessions(models.SubjectAdmin, user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "强制下线失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "该用户的所有会话已注销"})
+}
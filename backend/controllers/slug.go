@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"gin-blog/backend/database"
+	"gin-blog/backend/models"
+	"gin-blog/backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// isNumericParam 判断路径参数是否为数字 ID；纯数字永远按 ID 解析，因此不会生成纯数字的 slug
+func isNumericParam(param string) bool {
+	_, err := strconv.ParseUint(param, 10, 32)
+	return err == nil
+}
+
+// uniqueSlug 根据 source 生成在该表中唯一的 slug（包括已软删除的记录），冲突时追加 -2、-3 等后缀
+func uniqueSlug(db *gorm.DB, model interface{}, kind, source string, excludeID uint) (string, error) {
+	base := utils.Slugify(source)
+	if base == "" {
+		base = kind
+	} else if isNumericParam(base) {
+		base = kind + "-" + base
+	}
+
+	candidate := base
+	for i := 2; ; i++ {
+		var count int64
+		query := db.Unscoped().Model(model).Where("slug = ?", candidate)
+		if excludeID != 0 {
+			query = query.Where("id <> ?", excludeID)
+		}
+		if err := query.Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+// recordSlugRedirect 资源 slug 变化后保留旧 slug 的跳转；新 slug 若曾是旧链接则移除对应跳转
+func recordSlugRedirect(tx *gorm.DB, kind, oldSlug, newSlug string, targetID uint) error {
+	if err := tx.Where("kind = ? AND old_slug = ?", kind, newSlug).Delete(&models.SlugRedirect{}).Error; err != nil {
+		return err
+	}
+	if oldSlug == "" || oldSlug == newSlug {
+		return nil
+	}
+	redirect := models.SlugRedirect{Kind: kind, OldSlug: oldSlug, TargetID: targetID}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "kind"}, {Name: "old_slug"}},
+		DoUpdates: clause.AssignmentColumns([]string{"target_id"}),
+	}).Create(&redirect).Error
+}
+
+// resolveSlugID 将路径参数解析为资源 ID：数字按 ID 处理，否则先查当前 slug，再查旧 slug 跳转。
+// redirected 为 true 表示命中的是旧 slug。未找到时返回 gorm.ErrRecordNotFound。
+func resolveSlugID(model interface{}, kind, param string) (id uint, redirected bool, err error) {
+	if isNumericParam(param) {
+		parsed, _ := strconv.ParseUint(param, 10, 32)
+		return uint(parsed), false, nil
+	}
+
+	var row struct{ ID uint }
+	err = database.DB.Model(model).Select("id").Where("slug = ?", param).Take(&row).Error
+	if err == nil {
+		return row.ID, false, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return 0, false, err
+	}
+
+	var redirect models.SlugRedirect
+	if err := database.DB.Where("kind = ? AND old_slug = ?", kind, param).First(&redirect).Error; err != nil {
+		return 0, false, err
+	}
+	return redirect.TargetID, true, nil
+}
+
+// setPostSlug 根据 source 为文章重新生成 slug 并记录旧 slug 的跳转，返回是否发生变化
+func setPostSlug(tx *gorm.DB, post *models.Post, source string) (bool, error) {
+	slug, err := uniqueSlug(tx, &models.Post{}, models.SlugKindPost, source, post.ID)
+	if err != nil {
+		return false, err
+	}
+	if slug == post.Slug {
+		return false, nil
+	}
+	if post.ID != 0 {
+		if err := recordSlugRedirect(tx, models.SlugKindPost, post.Slug, slug, post.ID); err != nil {
+			return false, err
+		}
+	}
+	post.Slug = slug
+	return true, nil
+}
+
+// resolvePostParam 将路径中的文章 ID 或 slug 解析为文章 ID，失败时直接写入错误响应
+func resolvePostParam(c *gin.Context) (uint, bool) {
+	id, _, err := resolveSlugID(&models.Post{}, models.SlugKindPost, c.Param("id"))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "文章未找到"})
+			return 0, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查找文章失败"})
+		return 0, false
+	}
+	return id, true
+}
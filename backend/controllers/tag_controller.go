@@ -0,0 +1,299 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+
+	"gin-blog/backend/database"
+	"gin-blog/backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TagWithCount 标签及其可见文章数
+type TagWithCount struct {
+	models.Tag
+	PostCount int64 `json:"post_count"`
+}
+
+// normalizeTagName 去掉首尾空白并将连续空白合并为一个空格
+func normalizeTagName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// findTag 按 ID 或 slug 查找标签，失败时直接写入错误响应
+func findTag(c *gin.Context) (*models.Tag, bool) {
+	var tag models.Tag
+	id, _, err := resolveSlugID(&models.Tag{}, models.SlugKindTag, c.Param("id"))
+	if err == nil {
+		err = database.DB.First(&tag, id).Error
+	}
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "标签未找到"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查找标签失败"})
+		return nil, false
+	}
+	return &tag, true
+}
+
+// TagListQuery 标签列表参数
+type TagListQuery struct {
+	Sort     string `form:"sort"`      // count（默认）或 name
+	MinCount int64  `form:"min_count"` // 只返回文章数不少于该值的标签
+}
+
+// GetTags 返回所有标签及当前请求可见的文章数，可用于标签云
+func GetTags(c *gin.Context) {
+	var q TagListQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters: " + err.Error()})
+		return
+	}
+
+	visiblePosts := database.DB.Model(&models.Post{}).Select("posts.id")
+	if scope := visiblePostScope(c); scope != nil {
+		visiblePosts = scope(visiblePosts)
+	}
+	counts := database.DB.Table("post_tags").
+		Select("post_tags.tag_id, COUNT(*) AS post_count").
+		Where("post_tags.post_id IN (?)", visiblePosts).
+		Group("post_tags.tag_id")
+
+	db := database.DB.Model(&models.Tag{}).
+		Select("tags.*, COALESCE(counts.post_count, 0) AS post_count").
+		Joins("LEFT JOIN (?) AS counts ON counts.tag_id = tags.id", counts)
+	if q.MinCount > 0 {
+		db = db.Where("COALESCE(counts.post_count, 0) >= ?", q.MinCount)
+	}
+	switch q.Sort {
+	case "", "count":
+		db = db.Order("post_count desc").Order("tags.name asc")
+	case "name":
+		db = db.Order("tags.name asc")
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort 只能是 count 或 name"})
+		return
+	}
+
+	tags := []TagWithCount{}
+	if err := db.Scan(&tags).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取标签列表失败"})
+		return
+	}
+	c.JSON(http.StatusOK, tags)
+}
+
+type RenameTagInput struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// RenameTag 修改标签名称，旧 slug 保留跳转。新名称与其他标签重复时应使用合并接口。
+func RenameTag(c *gin.Context) {
+	tag, ok := findTag(c)
+	if !ok {
+		return
+	}
+
+	var input RenameTagInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	name := normalizeTagName(input.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "标签名不能为空"})
+		return
+	}
+	if name == tag.Name {
+		c.JSON(http.StatusOK, tag)
+		return
+	}
+
+	var conflict models.Tag
+	err := database.DB.Unscoped().Where("LOWER(name) = LOWER(?) AND id <> ?", name, tag.ID).First(&conflict).Error
+	if err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "已存在同名标签，请使用合并功能", "tag": conflict})
+		return
+	}
+	if err != gorm.ErrRecordNotFound {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "检查标签名称失败"})
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{"name": name}
+		slug, err := uniqueSlug(tx, &models.Tag{}, models.SlugKindTag, name, tag.ID)
+		if err != nil {
+			return err
+		}
+		if slug != tag.Slug {
+			if err := recordSlugRedirect(tx, models.SlugKindTag, tag.Slug, slug, tag.ID); err != nil {
+				return err
+			}
+			updates["slug"] = slug
+		}
+		return tx.Model(tag).Updates(updates).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "重命名标签失败"})
+		return
+	}
+
+	var updated models.Tag
+	if err := database.DB.First(&updated, tag.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取标签失败"})
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+// deleteTags 删除标签及其文章关联和 slug 跳转记录，标签被物理删除以便名称可以再次使用
+func deleteTags(tx *gorm.DB, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := tx.Table("post_tags").Where("tag_id IN ?", ids).Delete(nil).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("kind = ? AND target_id IN ?", models.SlugKindTag, ids).Delete(&models.SlugRedirect{}).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Where("id IN ?", ids).Delete(&models.Tag{}).Error
+}
+
+// DeleteTag 删除标签，文章本身不受影响
+func DeleteTag(c *gin.Context) {
+	tag, ok := findTag(c)
+	if !ok {
+		return
+	}
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		return deleteTags(tx, []uint{tag.ID})
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除标签失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "标签已删除"})
+}
+
+type MergeTagsInput struct {
+	SourceIDs []uint `json:"source_ids" binding:"required,min=1"`
+	TargetID  uint   `json:"target_id" binding:"required"`
+}
+
+// MergeTags 将多个标签合并到目标标签：文章关联改为指向目标标签，
+// 被合并标签的 slug 跳转到目标标签，随后删除被合并的标签。
+func MergeTags(c *gin.Context) {
+	var input MergeTagsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var target models.Tag
+	if err := database.DB.First(&target, input.TargetID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "目标标签未找到"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查找目标标签失败"})
+		return
+	}
+
+	sourceIDs := make([]uint, 0, len(input.SourceIDs))
+	for _, id := range input.SourceIDs {
+		if id != target.ID {
+			sourceIDs = append(sourceIDs, id)
+		}
+	}
+	var sources []models.Tag
+	if err := database.DB.Where("id IN ?", sourceIDs).Find(&sources).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查找待合并标签失败"})
+		return
+	}
+	if len(sources) == 0 || len(sources) != len(sourceIDs) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "待合并的标签不存在或与目标标签相同"})
+		return
+	}
+
+	var repointed int64
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		rows := []map[string]interface{}{}
+		var postIDs []uint
+		if err := tx.Table("post_tags").Distinct("post_id").Where("tag_id IN ?", sourceIDs).Pluck("post_id", &postIDs).Error; err != nil {
+			return err
+		}
+		for _, postID := range postIDs {
+			rows = append(rows, map[string]interface{}{"post_id": postID, "tag_id": target.ID})
+		}
+		if len(rows) > 0 {
+			if err := tx.Table("post_tags").Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error; err != nil {
+				return err
+			}
+		}
+		repointed = int64(len(postIDs))
+
+		// 原本指向被合并标签的旧 slug 一并改为指向目标标签
+		if err := tx.Model(&models.SlugRedirect{}).
+			Where("kind = ? AND target_id IN ?", models.SlugKindTag, sourceIDs).
+			Update("target_id", target.ID).Error; err != nil {
+			return err
+		}
+		if err := deleteTags(tx, sourceIDs); err != nil {
+			return err
+		}
+		for _, source := range sources {
+			if err := recordSlugRedirect(tx, models.SlugKindTag, source.Slug, target.Slug, target.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "合并标签失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "标签已合并",
+		"tag":         target,
+		"merged":      len(sources),
+		"posts_moved": repointed,
+	})
+}
+
+// CleanupTags 删除没有任何文章使用的标签，?dry_run=true 时只返回将被删除的标签
+func CleanupTags(c *gin.Context) {
+	var orphans []models.Tag
+	err := database.DB.Where("id NOT IN (?)", database.DB.Table("post_tags").
+		Select("post_tags.tag_id").
+		Joins("JOIN posts ON posts.id = post_tags.post_id AND posts.deleted_at IS NULL")).
+		Order("name asc").
+		Find(&orphans).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查找未使用的标签失败"})
+		return
+	}
+
+	if c.Query("dry_run") == "true" || len(orphans) == 0 {
+		c.JSON(http.StatusOK, gin.H{"deleted": 0, "tags": orphans})
+		return
+	}
+
+	ids := make([]uint, len(orphans))
+	for i := range orphans {
+		ids[i] = orphans[i].ID
+	}
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		return deleteTags(tx, ids)
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "清理标签失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deleted": len(orphans), "tags": orphans})
+}
@@ -0,0 +1,388 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"gin-blog/backend/database"
+	"gin-blog/backend/models"
+	"gin-blog/backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	loginChallengeTTL         = 5 * time.Minute
+	maxLoginChallengeFailures = 5
+	recoveryCodeCount         = 10
+)
+
+var (
+	errChallengeUsed       = errors.New("login challenge has already been used")
+	errInvalidSecondFactor = errors.New("invalid second factor")
+)
+
+// challengeAttempts 记录每个两步验证凭证（按 jti）的失败次数，达到上限后拒绝继续尝试。
+// 凭证只能成功使用一次由 revoked_tokens 中的记录保证，多实例和重启后同样有效。
+var challengeAttempts = struct {
+	sync.Mutex
+	entries map[string]*challengeAttempt
+}{entries: make(map[string]*challengeAttempt)}
+
+type challengeAttempt struct {
+	failures  int
+	expiresAt time.Time
+}
+
+// challengeExhausted 判断凭证的失败次数是否已达上限，并顺带清理过期记录
+func challengeExhausted(jti string) bool {
+	challengeAttempts.Lock()
+	defer challengeAttempts.Unlock()
+	now := time.Now()
+	for key, attempt := range challengeAttempts.entries {
+		if now.After(attempt.expiresAt) {
+			delete(challengeAttempts.entries, key)
+		}
+	}
+	attempt, ok := challengeAttempts.entries[jti]
+	return ok && attempt.failures >= maxLoginChallengeFailures
+}
+
+func recordChallengeFailure(jti string, expiresAt time.Time) {
+	challengeAttempts.Lock()
+	defer challengeAttempts.Unlock()
+	attempt, ok := challengeAttempts.entries[jti]
+	if !ok {
+		attempt = &challengeAttempt{expiresAt: expiresAt}
+		challengeAttempts.entries[jti] = attempt
+	}
+	attempt.failures++
+}
+
+// normalizeRecoveryCode 忽略大小写、空格和连字符，方便用户手动输入
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// generateRecoveryCodes 作废用户原有的恢复码并生成一组新的，明文只在此时返回一次
+func generateRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+	codes := make([]string, recoveryCodeCount)
+	records := make([]models.RecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		token, err := newSecureToken()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = token[:5] + "-" + token[5:10]
+		records[i] = models.RecoveryCode{UserID: userID, CodeHash: hashToken(normalizeRecoveryCode(codes[i]))}
+	}
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// verifySecondFactor 校验 TOTP 验证码或恢复码，两者都会在成功后被消耗，不能重复使用
+func verifySecondFactor(tx *gorm.DB, user *models.User, code, recoveryCode string) (bool, error) {
+	if code != "" {
+		step, ok := utils.ValidateTOTP(user.TOTPSecret, code, time.Now(), user.TOTPLastStep)
+		if !ok {
+			return false, nil
+		}
+		// 条件更新保证同一时间步的验证码只能使用一次
+		result := tx.Model(&models.User{}).
+			Where("id = ? AND totp_last_step < ?", user.ID, step).
+			Update("totp_last_step", step)
+		return result.RowsAffected == 1, result.Error
+	}
+	if recoveryCode != "" {
+		result := tx.Model(&models.RecoveryCode{}).
+			Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, hashToken(normalizeRecoveryCode(recoveryCode))).
+			Update("used_at", time.Now())
+		return result.RowsAffected == 1, result.Error
+	}
+	return false, nil
+}
+
+func remainingRecoveryCodes(userID uint) (int64, error) {
+	var count int64
+	err := database.DB.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+// respondLoginChallenge 密码验证通过但需要两步验证时，返回短期凭证而不是访问令牌
+func respondLoginChallenge(c *gin.Context, user *models.User) {
+	challenge, expiresAt, err := utils.SignLoginChallenge(&utils.LoginChallenge{UserID: user.ID, TokenVersion: user.TokenVersion}, loginChallengeTTL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"two_factor_required": true,
+		"challenge_token":     challenge,
+		"expires_at":          expiresAt,
+	})
+}
+
+type TwoFactorLoginInput struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+// VerifyTwoFactorLogin 登录第二步：使用密码验证后得到的凭证和 TOTP 验证码（或恢复码）换取访问令牌
+func VerifyTwoFactorLogin(c *gin.Context) {
+	var input TwoFactorLoginInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Code == "" && input.RecoveryCode == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Verification code or recovery code is required"})
+		return
+	}
+
+	challenge, err := utils.ParseLoginChallenge(input.ChallengeToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login challenge is invalid or has expired, please log in again"})
+		return
+	}
+	if challengeExhausted(challenge.ID) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login challenge is no longer valid, please log in again"})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, challenge.UserID).Error; err != nil ||
+		user.TokenVersion != challenge.TokenVersion || !user.TOTPEnabled {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login challenge is invalid or has expired, please log in again"})
+		return
+	}
+	if user.Disabled {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account has been disabled"})
+		return
+	}
+	if !checkLoginThrottle(c, user.Username) {
+		return
+	}
+
+	subject := adminSubject(&user)
+	var tokens *sessionTokens
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// 先写入凭证的 jti 占用凭证，并发的请求中只有一个能写入成功；
+		// 验证码错误时事务回滚，凭证在失败次数上限内仍可重试
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.RevokedToken{JTI: challenge.ID, ExpiresAt: challenge.ExpiresAt.Time})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errChallengeUsed
+		}
+
+		ok, err := verifySecondFactor(tx, &user, input.Code, input.RecoveryCode)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errInvalidSecondFactor
+		}
+		tokens, _, err = issueSession(tx, c, subject, "")
+		return err
+	})
+	switch {
+	case errors.Is(err, errChallengeUsed):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login challenge is no longer valid, please log in again"})
+		return
+	case errors.Is(err, errInvalidSecondFactor):
+		recordChallengeFailure(challenge.ID, challenge.ExpiresAt.Time)
+		recordLoginFailure(c, user.Username)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid verification code"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	clearLoginFailures(user.Username)
+	c.JSON(http.StatusOK, sessionResponse(subject, tokens))
+}
+
+// GetTwoFactorStatus 查看当前用户的两步验证状态
+func GetTwoFactorStatus(c *gin.Context) {
+	user := currentUser(c)
+	remaining, err := remainingRecoveryCodes(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":                  user.TOTPEnabled,
+		"recovery_codes_remaining": remaining,
+	})
+}
+
+// SetupTwoFactor 生成新的 TOTP 密钥并返回 otpauth:// 地址，需调用 EnableTwoFactor 确认后才生效
+func SetupTwoFactor(c *gin.Context) {
+	user := currentUser(c)
+	if user.TOTPEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate secret"})
+		return
+	}
+	if err := database.DB.Model(user).Updates(map[string]interface{}{"totp_secret": secret, "totp_last_step": 0}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"secret":           secret,
+		"provisioning_uri": utils.TOTPProvisioningURI(secret, siteTitle(), user.Username),
+	})
+}
+
+type EnableTwoFactorInput struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// EnableTwoFactor 用身份验证器生成的验证码确认密钥，启用两步验证并返回恢复码
+func EnableTwoFactor(c *gin.Context) {
+	user := currentUser(c)
+	var input EnableTwoFactorInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if user.TOTPEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+	if user.TOTPSecret == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor setup has not been started"})
+		return
+	}
+	step, ok := utils.ValidateTOTP(user.TOTPSecret, input.Code, time.Now(), 0)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid verification code"})
+		return
+	}
+
+	var codes []string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]interface{}{"totp_enabled": true, "totp_last_step": step}).Error; err != nil {
+			return err
+		}
+		var err error
+		codes, err = generateRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"enabled": true, "recovery_codes": codes})
+}
+
+type TwoFactorConfirmInput struct {
+	Password     string `json:"password" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// confirmTwoFactorChange 关闭两步验证或重新生成恢复码前，要求同时提供密码和第二因素
+func confirmTwoFactorChange(c *gin.Context, user *models.User) bool {
+	var input TwoFactorConfirmInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if !user.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return false
+	}
+	if user.CheckPassword(input.Password) != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid password"})
+		return false
+	}
+	ok, err := verifySecondFactor(database.DB, user, input.Code, input.RecoveryCode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return false
+	}
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid verification code"})
+		return false
+	}
+	return true
+}
+
+// disableTwoFactor 清除密钥和恢复码
+func disableTwoFactor(userID uint) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"totp_enabled":   false,
+			"totp_secret":    "",
+			"totp_last_step": 0,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+	})
+}
+
+// DisableTwoFactor 当前用户关闭两步验证
+func DisableTwoFactor(c *gin.Context) {
+	user := currentUser(c)
+	if !confirmTwoFactorChange(c, user) {
+		return
+	}
+	if err := disableTwoFactor(user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"enabled": false})
+}
+
+// RegenerateRecoveryCodes 作废旧的恢复码并生成新的一组
+func RegenerateRecoveryCodes(c *gin.Context) {
+	user := currentUser(c)
+	if !confirmTwoFactorChange(c, user) {
+		return
+	}
+	var codes []string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = generateRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recovery codes"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// ResetUserTwoFactor 管理员为丢失身份验证器和恢复码的用户关闭两步验证
+func ResetUserTwoFactor(c *gin.Context) {
+	user, ok := findUser(c)
+	if !ok {
+		return
+	}
+	if err := disableTwoFactor(user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "重置两步验证失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已关闭该用户的两步验证"})
+}
@@ -0,0 +1,424 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gin-blog/backend/database"
+	"gin-blog/backend/models"
+	"gin-blog/backend/storage"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	thumbnailMaxSide = 320
+	// 超过该像素数的图片不生成缩略图，避免解码超大图片耗尽内存
+	thumbnailMaxPixels   = 40_000_000
+	defaultMediaPageSize = 20
+	maxMediaPageSize     = 100
+)
+
+// allowedUploadTypes 允许上传的文件类型（按内容嗅探结果判断）及保存时使用的扩展名
+var allowedUploadTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+	"application/zip": ".zip",
+	"text/plain":      ".txt",
+}
+
+// sniffContentType 根据文件内容判断类型，忽略客户端声明的 Content-Type
+func sniffContentType(data []byte) (string, string, bool) {
+	contentType := http.DetectContentType(data)
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+	ext, ok := allowedUploadTypes[contentType]
+	return contentType, ext, ok
+}
+
+// makeThumbnail 生成最长边不超过 thumbnailMaxSide 的缩略图，返回编码后的数据和扩展名
+func makeThumbnail(data []byte, contentType string) (thumb []byte, ext string, width, height int, err error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", 0, 0, err
+	}
+	width, height = config.Width, config.Height
+	if width*height > thumbnailMaxPixels {
+		return nil, "", width, height, nil
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", width, height, err
+	}
+
+	scale := math.Min(1, float64(thumbnailMaxSide)/float64(max(width, height)))
+	tw, th := max(1, int(float64(width)*scale)), max(1, int(float64(height)*scale))
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Over, nil)
+
+	var buf bytes.Buffer
+	// PNG 和 GIF 可能带透明通道，缩略图使用 PNG；其余使用 JPEG
+	if contentType == "image/png" || contentType == "image/gif" {
+		err = png.Encode(&buf, dst)
+		ext = ".png"
+	} else {
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80})
+		ext = ".jpg"
+	}
+	return buf.Bytes(), ext, width, height, err
+}
+
+// linkMediaToPost 将媒体关联到文章，postID 为 0 时不做处理
+func linkMediaToPost(media *models.Media, postID uint) error {
+	if postID == 0 {
+		return nil
+	}
+	return database.DB.Table("post_media").
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(map[string]interface{}{"media_id": media.ID, "post_id": postID}).Error
+}
+
+// fillMediaPostIDs 为媒体列表填充关联的文章ID
+func fillMediaPostIDs(media []models.Media) error {
+	if len(media) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(media))
+	for i := range media {
+		ids[i] = media[i].ID
+	}
+
+	var rows []struct {
+		MediaID uint
+		PostID  uint
+	}
+	if err := database.DB.Table("post_media").Where("media_id IN ?", ids).Order("post_id").Find(&rows).Error; err != nil {
+		return err
+	}
+	postIDs := make(map[uint][]uint, len(media))
+	for _, row := range rows {
+		postIDs[row.MediaID] = append(postIDs[row.MediaID], row.PostID)
+	}
+	for i := range media {
+		media[i].PostIDs = postIDs[media[i].ID]
+		if media[i].PostIDs == nil {
+			media[i].PostIDs = []uint{}
+		}
+	}
+	return nil
+}
+
+// respondMedia 返回单个媒体文件及其关联文章
+func respondMedia(c *gin.Context, status int, media *models.Media, duplicate bool) {
+	list := []models.Media{*media}
+	if err := fillMediaPostIDs(list); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取媒体关联文章失败"})
+		return
+	}
+	c.JSON(status, gin.H{"media": list[0], "duplicate": duplicate})
+}
+
+// UploadFile 上传文件到媒体库，可通过 post_id 表单字段关联到当前用户可以修改的文章。
+// 内容相同的文件只保存一份，重复上传时直接返回已有记录。
+func UploadFile(c *gin.Context) {
+	maxSize := appConfig.Storage.MaxUploadSize
+	// 为 multipart 的边界和其他字段预留少量空间
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+1<<20)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("文件大小不能超过 %d 字节", maxSize)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请通过 file 字段上传文件"})
+		return
+	}
+	if fileHeader.Size > maxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("文件大小不能超过 %d 字节", maxSize)})
+		return
+	}
+
+	var postID uint
+	if value := c.PostForm("post_id"); value != "" {
+		parsed, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文章ID格式"})
+			return
+		}
+		var post models.Post
+		if err := database.DB.First(&post, uint(parsed)).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": "文章未找到"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查找文章失败"})
+			return
+		}
+		// 只能把媒体关联到自己可以修改的文章
+		if !canEditPost(currentUser(c), &post) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "您无权修改此文章"})
+			return
+		}
+		postID = post.ID
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "读取上传文件失败"})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "读取上传文件失败"})
+		return
+	}
+	if int64(len(data)) > maxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("文件大小不能超过 %d 字节", maxSize)})
+		return
+	}
+	if len(data) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "上传文件为空"})
+		return
+	}
+
+	contentType, ext, ok := sniffContentType(data)
+	if !ok {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "不支持的文件类型: " + contentType})
+		return
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	var existing models.Media
+	err = database.DB.Where("sha256 = ?", hash).First(&existing).Error
+	if err == nil {
+		if err := linkMediaToPost(&existing, postID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "关联文章失败"})
+			return
+		}
+		respondMedia(c, http.StatusOK, &existing, true)
+		return
+	}
+	if err != gorm.ErrRecordNotFound {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查找媒体文件失败"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	media := models.Media{
+		FileName:    filepath.Base(fileHeader.Filename),
+		StorageKey:  fmt.Sprintf("%s/%s%s", hash[:2], hash, ext),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		SHA256:      hash,
+		UploaderID:  c.GetUint("userID"),
+	}
+	// 存储 key 由内容哈希决定，并发上传相同文件时会写入同一个 key。
+	// 记录本次请求新建的文件，失败时只删除这些文件，不影响其他请求已经登记的记录。
+	var created []string
+	if err := saveMediaFile(ctx, media.StorageKey, data, contentType, &created); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存文件失败"})
+		return
+	}
+	media.URL = storage.Default.URL(media.StorageKey)
+
+	if strings.HasPrefix(contentType, "image/") {
+		thumb, thumbExt, width, height, err := makeThumbnail(data, contentType)
+		if err != nil {
+			log.Printf("Failed to generate thumbnail for %s: %v", media.StorageKey, err)
+		}
+		media.Width, media.Height = width, height
+		if len(thumb) > 0 {
+			thumbKey := fmt.Sprintf("thumbs/%s/%s%s", hash[:2], hash, thumbExt)
+			if err := saveMediaFile(ctx, thumbKey, thumb, "image/"+strings.TrimPrefix(thumbExt, "."), &created); err != nil {
+				log.Printf("Failed to save thumbnail for %s: %v", media.StorageKey, err)
+			} else {
+				media.ThumbnailKey = thumbKey
+				media.ThumbnailURL = storage.Default.URL(thumbKey)
+			}
+		}
+	}
+
+	if err := database.DB.Create(&media).Error; err != nil {
+		// 并发上传相同文件时唯一索引冲突，改为返回另一个请求创建的记录
+		if database.DB.Where("sha256 = ?", hash).First(&existing).Error == nil {
+			if err := linkMediaToPost(&existing, postID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "关联文章失败"})
+				return
+			}
+			respondMedia(c, http.StatusOK, &existing, true)
+			return
+		}
+		deleteStorageKeys(ctx, created)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存媒体记录失败"})
+		return
+	}
+	if err := linkMediaToPost(&media, postID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "关联文章失败"})
+		return
+	}
+	respondMedia(c, http.StatusCreated, &media, false)
+}
+
+// saveMediaFile 保存文件，文件原本不存在时把 key 追加到 created
+func saveMediaFile(ctx context.Context, key string, data []byte, contentType string, created *[]string) error {
+	exists, err := storage.Default.Exists(ctx, key)
+	if err != nil {
+		return err
+	}
+	if err := storage.Default.Save(ctx, key, bytes.NewReader(data), contentType); err != nil {
+		return err
+	}
+	if !exists {
+		*created = append(*created, key)
+	}
+	return nil
+}
+
+func deleteStorageKeys(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		if err := storage.Default.Delete(ctx, key); err != nil {
+			log.Printf("Failed to delete file %s: %v", key, err)
+		}
+	}
+}
+
+func removeMediaFiles(c *gin.Context, media *models.Media) {
+	ctx := c.Request.Context()
+	if err := storage.Default.Delete(ctx, media.StorageKey); err != nil {
+		log.Printf("Failed to delete file %s: %v", media.StorageKey, err)
+	}
+	if media.ThumbnailKey != "" {
+		if err := storage.Default.Delete(ctx, media.ThumbnailKey); err != nil {
+			log.Printf("Failed to delete thumbnail %s: %v", media.ThumbnailKey, err)
+		}
+	}
+}
+
+// MediaListQuery 媒体库列表的分页和筛选参数
+type MediaListQuery struct {
+	Page     int    `form:"page"`
+	PageSize int    `form:"page_size"`
+	Type     string `form:"type"` // image 或 file
+	PostID   *uint  `form:"post_id"`
+}
+
+// GetMediaList 分页列出媒体库中的文件，按上传时间倒序
+func GetMediaList(c *gin.Context) {
+	var q MediaListQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters: " + err.Error()})
+		return
+	}
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.PageSize < 1 {
+		q.PageSize = defaultMediaPageSize
+	}
+	if q.PageSize > maxMediaPageSize {
+		q.PageSize = maxMediaPageSize
+	}
+
+	db := database.DB.Model(&models.Media{})
+	switch q.Type {
+	case "":
+	case "image":
+		db = db.Where("content_type LIKE ?", "image/%")
+	case "file":
+		db = db.Where("content_type NOT LIKE ?", "image/%")
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type 只能是 image 或 file"})
+		return
+	}
+	if q.PostID != nil {
+		db = db.Where("id IN (?)", database.DB.Table("post_media").Select("media_id").Where("post_id = ?", *q.PostID))
+	}
+
+	var total int64
+	if err := db.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取媒体列表失败"})
+		return
+	}
+
+	var media []models.Media
+	err := db.Order("created_at desc, id desc").
+		Limit(q.PageSize).
+		Offset((q.Page - 1) * q.PageSize).
+		Find(&media).Error
+	if err == nil {
+		err = fillMediaPostIDs(media)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取媒体列表失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":        media,
+		"total":       total,
+		"page":        q.Page,
+		"page_size":   q.PageSize,
+		"total_pages": (total + int64(q.PageSize) - 1) / int64(q.PageSize),
+	})
+}
+
+// DeleteMedia 删除媒体文件及其与文章的关联。文章正文中引用的地址不会被修改。
+func DeleteMedia(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的媒体ID格式"})
+		return
+	}
+
+	var media models.Media
+	if err := database.DB.First(&media, uint(id)).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "媒体文件未找到"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查找媒体文件失败"})
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&media).Association("Posts").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(&media).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除媒体记录失败"})
+		return
+	}
+	removeMediaFiles(c, &media)
+	c.JSON(http.StatusOK, gin.H{"message": "媒体文件已删除"})
+}
@@ -0,0 +1,251 @@
+package controllers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gin-blog/backend/database"
+	"gin-blog/backend/models"
+	"gin-blog/backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func findUser(c *gin.Context) (*models.User, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID格式"})
+		return nil, false
+	}
+	var user models.User
+	if err := database.DB.First(&user, uint(id)).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "用户未找到"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查找用户失败"})
+		return nil, false
+	}
+	return &user, true
+}
+
+// isLastActiveOwner 判断用户是否为唯一一个未被禁用的所有者，站点至少需要保留一个所有者
+func isLastActiveOwner(user *models.User) (bool, error) {
+	if user.Role != models.RoleOwner || user.Disabled {
+		return false, nil
+	}
+	var count int64
+	err := database.DB.Model(&models.User{}).
+		Where("role = ? AND disabled = ? AND id <> ?", models.RoleOwner, false, user.ID).
+		Count(&count).Error
+	return count == 0, err
+}
+
+// GetUsers 列出所有后台用户，包括已禁用和尚未接受邀请的用户
+func GetUsers(c *gin.Context) {
+	var users []models.User
+	if err := database.DB.Order("id asc").Find(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户列表失败"})
+		return
+	}
+
+	result := make([]gin.H, len(users))
+	for i := range users {
+		result[i] = gin.H{
+			"id":                 users[i].ID,
+			"created_at":         users[i].CreatedAt,
+			"username":           users[i].Username,
+			"role":               users[i].Role,
+			"disabled":           users[i].Disabled,
+			"pending":            users[i].IsPending(),
+			"two_factor_enabled": users[i].TOTPEnabled,
+			"locked_until":       usernameLockedUntil(users[i].Username),
+			"invite_expires_at":  users[i].InviteExpiresAt,
+		}
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+type InviteUserInput struct {
+	Username string `json:"username" binding:"required"`
+	Role     string `json:"role" binding:"required"`
+}
+
+// InviteUser 创建一个待激活的用户并返回一次性邀请码，被邀请人通过邀请码设置密码后即可登录。
+// 对尚未接受邀请的用户再次邀请会生成新的邀请码。
+func InviteUser(c *gin.Context) {
+	var input InviteUserInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	username := strings.TrimSpace(input.Username)
+	if username == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "用户名不能为空"})
+		return
+	}
+	if !models.IsValidRole(input.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的角色: " + input.Role})
+		return
+	}
+
+	var user models.User
+	err := database.DB.Where("username = ?", username).First(&user).Error
+	if err == nil && !user.IsPending() {
+		c.JSON(http.StatusConflict, gin.H{"error": "该用户名已被使用"})
+		return
+	}
+	if err != nil && err != gorm.ErrRecordNotFound {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查找用户失败"})
+		return
+	}
+
+	token, err := newSecureToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成邀请码失败"})
+		return
+	}
+	expiresAt := time.Now().Add(appConfig.Auth.InviteTTL)
+
+	user.Username = username
+	user.Role = input.Role
+	user.InviteTokenHash = hashToken(token)
+	user.InviteExpiresAt = &expiresAt
+	if err := database.DB.Save(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建用户失败"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"user":         user,
+		"invite_token": token,
+		"invite_url":   siteURL() + "/admin/accept-invite?token=" + url.QueryEscape(token),
+		"expires_at":   expiresAt,
+	})
+}
+
+type AcceptInviteInput struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8"`
+}
+
+// AcceptInvite 被邀请人使用邀请码设置密码，成功后直接登录
+func AcceptInvite(c *gin.Context) {
+	var input AcceptInviteInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	err := database.DB.Where("invite_token_hash = ?", hashToken(input.Token)).First(&user).Error
+	if err != nil || user.InviteExpiresAt == nil || time.Now().After(*user.InviteExpiresAt) || user.Disabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "邀请码无效或已过期"})
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(input.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "处理密码失败"})
+		return
+	}
+	err = database.DB.Model(&user).Updates(map[string]interface{}{
+		"password":          hashedPassword,
+		"invite_token_hash": "",
+		"invite_expires_at": nil,
+	}).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "激活账号失败"})
+		return
+	}
+
+	subject := adminSubject(&user)
+	tokens, _, err := issueSession(database.DB, c, subject, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	c.JSON(http.StatusOK, sessionResponse(subject, tokens))
+}
+
+type ChangeUserRoleInput struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// ChangeUserRole 修改用户角色，不能降级最后一个所有者
+func ChangeUserRole(c *gin.Context) {
+	user, ok := findUser(c)
+	if !ok {
+		return
+	}
+	var input ChangeUserRoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !models.IsValidRole(input.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的角色: " + input.Role})
+		return
+	}
+
+	if input.Role != models.RoleOwner {
+		last, err := isLastActiveOwner(user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "检查所有者失败"})
+			return
+		}
+		if last {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "站点至少需要保留一个所有者"})
+			return
+		}
+	}
+
+	if err := database.DB.Model(user).Update("role", input.Role).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "修改角色失败"})
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+type SetUserStatusInput struct {
+	Disabled *bool `json:"disabled" binding:"required"`
+}
+
+// SetUserStatus 禁用或启用用户，被禁用用户的已有 token 会立即失效
+func SetUserStatus(c *gin.Context) {
+	user, ok := findUser(c)
+	if !ok {
+		return
+	}
+	var input SetUserStatusInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if *input.Disabled {
+		if user.ID == c.GetUint("userID") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "不能禁用自己的账号"})
+			return
+		}
+		last, err := isLastActiveOwner(user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "检查所有者失败"})
+			return
+		}
+		if last {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "站点至少需要保留一个所有者"})
+			return
+		}
+	}
+
+	if err := database.DB.Model(user).Update("disabled", *input.Disabled).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "修改用户状态失败"})
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"container/list"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"gin-blog/backend/database"
+	"gin-blog/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// viewTracker 在内存中累积文章浏览量并定期批量写入数据库。
+// 同一读者在去重窗口内重复访问同一篇文章只计一次。
+type viewTracker struct {
+	mu     sync.Mutex
+	window time.Duration
+	// maxSeen 去重记录的数量上限，超出时淘汰最早的记录，避免大量不同读者耗尽内存
+	maxSeen int
+	// seen 指向 order 中的元素；order 按记录时间从新到旧排列，元素值为 *seenEntry
+	seen    map[string]*list.Element
+	order   *list.List
+	pending map[uint]int64
+}
+
+type seenEntry struct {
+	key string
+	at  time.Time
+}
+
+var views = &viewTracker{
+	window:  appConfig.Views.DedupWindow,
+	maxSeen: appConfig.Views.DedupMaxEntries,
+	seen:    make(map[string]*list.Element),
+	order:   list.New(),
+	pending: make(map[uint]int64),
+}
+
+// Record 记录一次浏览，返回该次浏览是否被计数
+func (t *viewTracker) Record(postID uint, reader string, now time.Time) bool {
+	key := strconv.FormatUint(uint64(postID), 10) + "|" + reader
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if elem, ok := t.seen[key]; ok {
+		entry := elem.Value.(*seenEntry)
+		if now.Sub(entry.at) < t.window {
+			return false
+		}
+		entry.at = now
+		t.order.MoveToFront(elem)
+	} else {
+		t.seen[key] = t.order.PushFront(&seenEntry{key: key, at: now})
+		for t.maxSeen > 0 && t.order.Len() > t.maxSeen {
+			t.removeSeen(t.order.Back())
+		}
+	}
+	t.pending[postID]++
+	return true
+}
+
+func (t *viewTracker) removeSeen(elem *list.Element) {
+	t.order.Remove(elem)
+	delete(t.seen, elem.Value.(*seenEntry).key)
+}
+
+// Pending 返回某篇文章尚未写入数据库的浏览量
+func (t *viewTracker) Pending(postID uint) int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.pending[postID]
+}
+
+// PendingTotal 返回所有尚未写入数据库的浏览量
+func (t *viewTracker) PendingTotal() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var total int64
+	for _, n := range t.pending {
+		total += n
+	}
+	return total
+}
+
+// Flush 将累积的浏览量写入数据库，并清理过期的去重记录
+func (t *viewTracker) Flush(now time.Time) error {
+	t.mu.Lock()
+	batch := t.pending
+	t.pending = make(map[uint]int64)
+	for elem := t.order.Back(); elem != nil && now.Sub(elem.Value.(*seenEntry).at) >= t.window; elem = t.order.Back() {
+		t.removeSeen(elem)
+	}
+	t.mu.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		for postID, n := range batch {
+			if err := tx.Model(&models.Post{}).Where("id = ?", postID).
+				UpdateColumn("views_count", gorm.Expr("views_count + ?", n)).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		// 写入失败时放回缓冲区，等待下次重试
+		t.mu.Lock()
+		for postID, n := range batch {
+			t.pending[postID] += n
+		}
+		t.mu.Unlock()
+	}
+	return err
+}
+
+// StartViewTracker 启动浏览量的后台批量写入，返回的函数用于在退出前写入剩余数据
+func StartViewTracker() func() {
+	views.mu.Lock()
+	views.window = appConfig.Views.DedupWindow
+	views.maxSeen = appConfig.Views.DedupMaxEntries
+	views.mu.Unlock()
+	interval := appConfig.Views.FlushInterval
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			if err := views.Flush(now); err != nil {
+				log.Printf("Failed to flush post views: %v", err)
+			}
+		}
+	}()
+
+	return func() {
+		if err := views.Flush(time.Now()); err != nil {
+			log.Printf("Failed to flush post views on shutdown: %v", err)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package database
+
+import (
+	"html"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"gin-blog/backend/models"
+	"gorm.io/gorm"
+)
+
+// 全文检索基于 SQLite FTS5，需要使用 `-tags sqlite_fts5` 编译，索引表 posts_fts 由迁移创建。
+// 未启用 FTS5、使用 PostgreSQL/MySQL 或查询词过短（trigram 分词至少需要 3 个字符）时退化为 LIKE 查询。
+
+const (
+	highlightOpen  = "\x01"
+	highlightClose = "\x02"
+	snippetRunes   = 80
+)
+
+// SearchEnabled 表示当前数据库是否支持 FTS5 全文索引
+var SearchEnabled bool
+
+// LikeCondition 返回不区分大小写、以反斜杠转义的 LIKE 条件。
+// PostgreSQL 的 LIKE 区分大小写，需要使用 ILIKE；MySQL 默认即以反斜杠转义，且不接受 '\' 字面量。
+func LikeCondition(db *gorm.DB, column string) string {
+	switch db.Dialector.Name() {
+	case "postgres":
+		return column + ` ILIKE ? ESCAPE '\'`
+	case "mysql":
+		return column + " LIKE ?"
+	default:
+		return column + ` LIKE ? ESCAPE '\'`
+	}
+}
+
+// PostSearchHit 一条文章检索结果
+type PostSearchHit struct {
+	PostID         uint
+	Rank           float64
+	TitleHighlight string
+	Snippet        string
+}
+
+// detectSearchIndex 检查迁移创建的 FTS5 虚拟表在当前数据库和编译选项下是否可用
+func detectSearchIndex(db *gorm.DB) {
+	SearchEnabled = false
+	if db.Dialector.Name() != "sqlite" {
+		log.Printf("Info: full-text search index requires SQLite, using LIKE search on %s.", db.Dialector.Name())
+		return
+	}
+	if err := db.Exec("SELECT rowid FROM posts_fts LIMIT 0").Error; err != nil {
+		log.Printf("Warning: full-text search index unavailable, falling back to LIKE search: %v", err)
+		return
+	}
+	SearchEnabled = true
+}
+
+// IndexPost 写入或更新文章的全文索引
+func IndexPost(tx *gorm.DB, post *models.Post) error {
+	if !SearchEnabled {
+		return nil
+	}
+	if err := tx.Exec("DELETE FROM posts_fts WHERE rowid = ?", post.ID).Error; err != nil {
+		return err
+	}
+	return tx.Exec("INSERT INTO posts_fts(rowid, title, content) VALUES (?, ?, ?)", post.ID, post.Title, post.Content).Error
+}
+
+// RemovePostFromIndex 从全文索引中删除文章
+func RemovePostFromIndex(tx *gorm.DB, postID uint) error {
+	if !SearchEnabled {
+		return nil
+	}
+	return tx.Exec("DELETE FROM posts_fts WHERE rowid = ?", postID).Error
+}
+
+// searchTerms 将查询拆分为关键词
+func searchTerms(query string) []string {
+	return strings.Fields(query)
+}
+
+// canUseFTS 判断该查询能否走 FTS5 索引
+func canUseFTS(terms []string) bool {
+	if !SearchEnabled || len(terms) == 0 {
+		return false
+	}
+	for _, term := range terms {
+		if utf8.RuneCountInString(term) < 3 {
+			return false
+		}
+	}
+	return true
+}
+
+// ftsMatchExpr 将每个关键词作为短语引用，多个关键词之间为 AND 关系
+func ftsMatchExpr(terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+	}
+	return strings.Join(quoted, " ")
+}
+
+// SearchPosts 按相关度检索文章，scope 用于追加对 posts 表的额外过滤条件
+func SearchPosts(query string, limit, offset int, scope func(*gorm.DB) *gorm.DB) ([]PostSearchHit, int64, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, 0, nil
+	}
+	if canUseFTS(terms) {
+		return searchPostsFTS(terms, limit, offset, scope)
+	}
+	return searchPostsLike(terms, limit, offset, scope)
+}
+
+func searchPostsFTS(terms []string, limit, offset int, scope func(*gorm.DB) *gorm.DB) ([]PostSearchHit, int64, error) {
+	match := ftsMatchExpr(terms)
+	base := DB.Table("posts_fts").
+		Joins("JOIN posts ON posts.id = posts_fts.rowid AND posts.deleted_at IS NULL").
+		Where("posts_fts MATCH ?", match)
+	if scope != nil {
+		base = scope(base)
+	}
+
+	var total int64
+	if err := base.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var rows []struct {
+		PostID         uint
+		Score          float64
+		TitleHighlight string
+		Snippet        string
+	}
+	err := base.Session(&gorm.Session{}).
+		Select("posts.id AS post_id, bm25(posts_fts, 10.0, 1.0) AS score, "+
+			"highlight(posts_fts, 0, ?, ?) AS title_highlight, "+
+			"snippet(posts_fts, 1, ?, ?, '…', 64) AS snippet",
+			highlightOpen, highlightClose, highlightOpen, highlightClose).
+		Order("score").
+		Limit(limit).
+		Offset(offset).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	hits := make([]PostSearchHit, len(rows))
+	for i, row := range rows {
+		hits[i] = PostSearchHit{
+			PostID:         row.PostID,
+			Rank:           row.Score,
+			TitleHighlight: renderHighlight(row.TitleHighlight),
+			Snippet:        renderHighlight(row.Snippet),
+		}
+	}
+	return hits, total, nil
+}
+
+func searchPostsLike(terms []string, limit, offset int, scope func(*gorm.DB) *gorm.DB) ([]PostSearchHit, int64, error) {
+	base := DB.Model(&models.Post{})
+	for _, term := range terms {
+		pattern := "%" + EscapeLike(term) + "%"
+		base = base.Where("("+LikeCondition(DB, "posts.title")+" OR "+LikeCondition(DB, "posts.content")+")", pattern, pattern)
+	}
+	if scope != nil {
+		base = scope(base)
+	}
+
+	var total int64
+	if err := base.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var posts []models.Post
+	if err := base.Session(&gorm.Session{}).Order("posts.created_at desc").Limit(limit).Offset(offset).Find(&posts).Error; err != nil {
+		return nil, 0, err
+	}
+
+	hits := make([]PostSearchHit, len(posts))
+	for i, post := range posts {
+		hits[i] = PostSearchHit{
+			PostID:         post.ID,
+			TitleHighlight: renderHighlight(markTerms(post.Title, terms)),
+			Snippet:        renderHighlight(likeSnippet(post.Content, terms)),
+		}
+	}
+	return hits, total, nil
+}
+
+// EscapeLike 转义 LIKE 模式中的 %、_ 和反斜杠，配合 LikeCondition 使用
+func EscapeLike(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(s)
+}
+
+// markTerms 用高亮标记包裹所有命中的关键词（不区分大小写）
+func markTerms(text string, terms []string) string {
+	lower := strings.ToLower(text)
+	if len(lower) != len(text) {
+		// 个别字符转小写后字节长度会变化，此时退化为区分大小写的匹配
+		lower = text
+	}
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		matched := 0
+		for _, term := range terms {
+			t := strings.ToLower(term)
+			if t != "" && strings.HasPrefix(lower[i:], t) && len(t) > matched {
+				matched = len(t)
+			}
+		}
+		if matched > 0 {
+			b.WriteString(highlightOpen + text[i:i+matched] + highlightClose)
+			i += matched
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(text[i:])
+		b.WriteString(text[i : i+size])
+		i += size
+	}
+	return b.String()
+}
+
+// likeSnippet 截取首个命中位置附近的一段正文
+func likeSnippet(content string, terms []string) string {
+	lower := strings.ToLower(content)
+	pos := -1
+	for _, term := range terms {
+		if idx := strings.Index(lower, strings.ToLower(term)); idx >= 0 && (pos < 0 || idx < pos) {
+			pos = idx
+		}
+	}
+
+	runes := []rune(content)
+	start := 0
+	if pos > 0 {
+		// 转小写可能改变字节长度（如 Ⱥ 由 2 字节变为 3 字节），但逐个字符一一对应，
+		// 因此按 lower 中的字符数定位，不能用字节偏移截取 content
+		start = utf8.RuneCountInString(lower[:pos]) - snippetRunes/4
+		if start < 0 {
+			start = 0
+		}
+	}
+	end := start + snippetRunes
+	if end > len(runes) {
+		end = len(runes)
+	}
+
+	snippet := string(runes[start:end])
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(runes) {
+		snippet += "…"
+	}
+	return markTerms(snippet, terms)
+}
+
+// renderHighlight 转义 HTML 后再把高亮标记替换为 <mark> 标签
+func renderHighlight(s string) string {
+	s = html.EscapeString(s)
+	s = strings.ReplaceAll(s, highlightOpen, "<mark>")
+	return strings.ReplaceAll(s, highlightClose, "</mark>")
+}
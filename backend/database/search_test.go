@@ -0,0 +1,62 @@
+package database
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLikeSnippetNonASCII(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		terms   []string
+		want    string
+	}{
+		{
+			// 转小写后变长：命中位置的字节偏移超过原文长度
+			name:    "lowercase grows",
+			content: strings.Repeat("Ⱥ", 100) + " needle",
+			terms:   []string{"needle"},
+			want:    "…" + strings.Repeat("Ⱥ", 19) + " " + highlightOpen + "needle" + highlightClose,
+		},
+		{
+			// 转小写后变短：İ 为 2 字节，小写 i 为 1 字节
+			name:    "lowercase shrinks",
+			content: strings.Repeat("İ", 100) + " needle",
+			terms:   []string{"NEEDLE"},
+			want:    "…" + strings.Repeat("İ", 19) + " " + highlightOpen + "needle" + highlightClose,
+		},
+		{
+			name:    "chinese",
+			content: strings.Repeat("文章", 50) + "检索关键词" + strings.Repeat("内容", 50),
+			terms:   []string{"关键词"},
+			want: "…" + strings.Repeat("文章", 9) + "检索" + highlightOpen + "关键词" + highlightClose +
+				strings.Repeat("内容", 28) + "内…",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := likeSnippet(tt.content, tt.terms); got != tt.want {
+				t.Errorf("likeSnippet() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkTermsNonASCII(t *testing.T) {
+	tests := []struct {
+		title string
+		terms []string
+		want  string
+	}{
+		{"Go 语言入门", []string{"语言"}, "Go " + highlightOpen + "语言" + highlightClose + "入门"},
+		{"Straße GUIDE", []string{"guide"}, "Straße " + highlightOpen + "GUIDE" + highlightClose},
+		// 长度变化时退化为区分大小写的匹配
+		{"İstanbul travel", []string{"travel"}, "İstanbul " + highlightOpen + "travel" + highlightClose},
+	}
+	for _, tt := range tests {
+		if got := markTerms(tt.title, tt.terms); got != tt.want {
+			t.Errorf("markTerms(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
@@ -3,33 +3,83 @@ package database
 import (
 	"fmt"
 	"log"
-	"os"
+	"strings"
 
-	"gin-blog/backend/models"
+	"gin-blog/backend/config"
+	"gin-blog/backend/migrations"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
 var DB *gorm.DB
 
-func ConnectDatabase() {
-	dbName := os.Getenv("DB_NAME")
-	if dbName == "" {
-		dbName = "gin_blog.db"
+// dialector 根据配置选择数据库驱动
+func dialector(cfg config.DatabaseConfig) (gorm.Dialector, error) {
+	switch cfg.Driver {
+	case config.DriverSQLite, "":
+		dsn := cfg.DSN
+		if dsn == "" {
+			dsn = cfg.Name
+		}
+		return sqlite.Open(dsn), nil
+	case config.DriverPostgres:
+		return postgres.Open(cfg.DSN), nil
+	case config.DriverMySQL:
+		// 不解析时间时 DATETIME 列无法扫描到 time.Time
+		dsn := cfg.DSN
+		if !strings.Contains(dsn, "parseTime=") {
+			if strings.Contains(dsn, "?") {
+				dsn += "&parseTime=true"
+			} else {
+				dsn += "?parseTime=true"
+			}
+		}
+		return mysql.Open(dsn), nil
 	}
+	return nil, fmt.Errorf("unsupported database driver %q", cfg.Driver)
+}
 
-	database, err := gorm.Open(sqlite.Open(dbName), &gorm.Config{})
+func ConnectDatabase(cfg config.DatabaseConfig) {
+	dialect, err := dialector(cfg)
+	if err != nil {
+		log.Fatal("无法连接到数据库!", err)
+	}
+	database, err := gorm.Open(dialect, &gorm.Config{})
 	if err != nil {
 		log.Fatal("无法连接到数据库!", err)
 	}
 
-	fmt.Println("数据库连接成功打开")
-
-	err = database.AutoMigrate(&models.User{}, &models.Post{}, &models.Tag{}, &models.Category{}, &models.GuestUser{}, &models.Comment{})
+	sqlDB, err := database.DB()
 	if err != nil {
-		log.Fatal("数据库迁移失败!", err)
+		log.Fatal("无法获取数据库连接池!", err)
+	}
+	if cfg.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
 	}
-	fmt.Println("数据库已迁移")
+	if cfg.ConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	}
+	if cfg.ConnMaxIdleTime > 0 {
+		sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	}
+
+	fmt.Printf("数据库连接成功打开 (%s)\n", database.Dialector.Name())
 
 	DB = database
+}
+
+// PrepareSchema 检查版本化迁移并检测全文索引是否可用。生产环境存在未执行的迁移时拒绝启动，
+// 需先运行 `migrate up`；开发环境自动执行。
+func PrepareSchema(production bool) {
+	if err := migrations.EnsureUpToDate(DB, production); err != nil {
+		log.Fatal("数据库迁移失败! ", err)
+	}
+	fmt.Println("数据库已是最新版本")
+
+	detectSearchIndex(DB)
 }
\ No newline at end of file
@@ -3,22 +3,28 @@ package main
 import (
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"gin-blog/backend/config"
+	"gin-blog/backend/controllers"
 	"gin-blog/backend/database"
 	"gin-blog/backend/models"
+	"gin-blog/backend/oauth"
 	"gin-blog/backend/routes"
+	"gin-blog/backend/storage"
 	"gin-blog/backend/utils"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv" // 确保导入
 	"gorm.io/gorm"
 )
 
-// setupAdminUser 从 .env 文件读取管理员凭据并在数据库中创建/更新管理员用户
-func setupAdminUser() {
-	adminUsername := os.Getenv("ADMIN_USERNAME")
-	adminPassword := os.Getenv("ADMIN_PASSWORD")
+// setupAdminUser 根据配置中的管理员凭据在数据库中创建/更新管理员用户
+func setupAdminUser(admin config.AdminConfig) {
+	adminUsername := admin.Username
+	adminPassword := admin.Password
 
 	if adminUsername == "" || adminPassword == "" {
 		log.Println("ADMIN_USERNAME or ADMIN_PASSWORD not set in .env. Skipping admin user setup.")
@@ -40,6 +46,7 @@ func setupAdminUser() {
 			adminUser = models.User{
 				Username: adminUsername,
 				Password: hashedPassword,
+				Role:     models.RoleOwner,
 			}
 			if createErr := database.DB.Create(&adminUser).Error; createErr != nil {
 				log.Fatalf("Failed to create admin user '%s': %v", adminUsername, createErr)
@@ -59,12 +66,27 @@ func setupAdminUser() {
 			log.Fatalf("Failed to hash admin password for update: %v", hashErr)
 			return
 		}
-		if adminUser.Password != hashedPassword {
+		if adminUser.Role != models.RoleOwner || adminUser.Disabled {
+			// .env 中配置的管理员始终是可登录的站点所有者
+			if updateErr := database.DB.Model(&adminUser).Updates(map[string]interface{}{"role": models.RoleOwner, "disabled": false}).Error; updateErr != nil {
+				log.Fatalf("Failed to grant owner role to admin user '%s': %v", adminUsername, updateErr)
+				return
+			}
+			adminUser.Role, adminUser.Disabled = models.RoleOwner, false
+			log.Printf("Admin user '%s' granted owner role.", adminUsername)
+		}
+		// bcrypt 每次生成的哈希都不同，需用 CheckPassword 判断密码是否真的变化
+		if adminUser.CheckPassword(adminPassword) != nil {
 			adminUser.Password = hashedPassword
 			if updateErr := database.DB.Save(&adminUser).Error; updateErr != nil {
 				log.Fatalf("Failed to update password for admin user '%s': %v", adminUsername, updateErr)
 				return
 			}
+			// 密码变更后旧密码登录得到的会话全部失效
+			if revokeErr := controllers.RevokeAllSessions(models.SubjectAdmin, adminUser.ID); revokeErr != nil {
+				log.Fatalf("Failed to revoke sessions for admin user '%s': %v", adminUsername, revokeErr)
+				return
+			}
 			log.Printf("Password for admin user '%s' updated successfully based on .env configuration.", adminUsername)
 		} else {
 			log.Printf("Password for admin user '%s' is already up-to-date with .env configuration.", adminUsername)
@@ -72,39 +94,111 @@ func setupAdminUser() {
 	}
 }
 
+// ensureSiteOwner 升级前创建的用户默认没有所有者角色，此时将最早创建的用户设为所有者
+func ensureSiteOwner() {
+	var count int64
+	if err := database.DB.Model(&models.User{}).Where("role = ?", models.RoleOwner).Count(&count).Error; err != nil {
+		log.Printf("Failed to check site owner: %v", err)
+		return
+	}
+	if count > 0 {
+		return
+	}
+	var first models.User
+	if err := database.DB.Order("id asc").First(&first).Error; err != nil {
+		return
+	}
+	if err := database.DB.Model(&first).Update("role", models.RoleOwner).Error; err != nil {
+		log.Printf("Failed to grant owner role to user '%s': %v", first.Username, err)
+		return
+	}
+	log.Printf("No site owner found, granted owner role to user '%s'.", first.Username)
+}
+
+// publishDuePosts 将发布时间已到的定时文章切换为已发布状态
+func publishDuePosts() {
+	result := database.DB.Model(&models.Post{}).
+		Where("status = ? AND publish_at <= ?", models.PostStatusScheduled, time.Now()).
+		Updates(map[string]interface{}{
+			"status":       models.PostStatusPublished,
+			"published_at": gorm.Expr("publish_at"),
+		})
+	if result.Error != nil {
+		log.Printf("Failed to publish scheduled posts: %v", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Published %d scheduled post(s).", result.RowsAffected)
+	}
+}
+
+// startPostScheduler 在后台定期检查并发布到期的定时文章
+func startPostScheduler(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			publishDuePosts()
+			<-ticker.C
+		}
+	}()
+	log.Printf("Post scheduler started, checking every %s.", interval)
+}
+
 func main() {
-	// 优先尝试加载 .env.local
-	errLocal := godotenv.Load(".env.local")
-	if errLocal != nil {
-		// 如果 .env.local 加载失败（例如文件不存在），则尝试加载 .env
-		log.Println("Info: .env.local file not found or failed to load, trying .env file.")
-		errEnv := godotenv.Load() // 默认加载 .env
-		if errEnv != nil {
-			log.Println("Warning: Neither .env.local nor .env file could be loaded. Using default or system environment variables. Error for .env:", errEnv)
-		} else {
-			log.Println("Info: .env file loaded successfully.")
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// server migrate <up|down|status|create> 只处理迁移，不启动服务
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(cfg, os.Args[2:]); err != nil {
+			log.Fatalf("migrate: %v", err)
 		}
-	} else {
-		log.Println("Info: .env.local file loaded successfully.")
+		return
 	}
 
-	database.ConnectDatabase()
-	setupAdminUser() // 确保管理员用户已设置
+	log.Printf("Running in %s mode.", cfg.Env)
+	utils.ConfigureJWT(cfg.Auth.JWTSecret, cfg.Auth.AccessTokenTTL)
+	controllers.Configure(cfg)
+
+	database.ConnectDatabase(cfg.Database)
+	database.PrepareSchema(cfg.IsProduction())
+	utils.TokenRevocationCheck = controllers.CheckTokenRevocation
+	storage.SetupStorage(cfg.Storage)
+	oauth.SetupProviders(cfg.OAuth)
+	setupAdminUser(cfg.Admin) // 确保管理员用户已设置
+	ensureSiteOwner()
+	startPostScheduler(cfg.Scheduler.PostInterval)
 
+	// 退出前写入内存中尚未落库的浏览量
+	flushViews := controllers.StartViewTracker()
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+		<-quit
+		flushViews()
+		os.Exit(0)
+	}()
+
+	if cfg.GinMode != "" {
+		gin.SetMode(cfg.GinMode)
+	}
 	r := gin.Default()
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
 
-	config := cors.DefaultConfig()
-	config.AllowOrigins = []string{"http://localhost:3000"} // 前端地址
-	config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization"}
-	r.Use(cors.New(config))
+	corsConfig := cors.DefaultConfig()
+	corsConfig.AllowOrigins = cfg.CORS.AllowOrigins // 前端地址
+	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
+	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization"}
+	r.Use(cors.New(corsConfig))
 
-	routes.SetupRouter(r)
+	routes.SetupRouter(r, cfg)
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080" // 默认端口
-	}
+	port := cfg.Port
 	log.Printf("Server is running on port %s", port)
 	if err := r.Run(":" + port); err != nil {
 		log.Fatal("Failed to run server: ", err)
@@ -1,13 +1,49 @@
 package middlewares
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 
+	"gin-blog/backend/database"
+	"gin-blog/backend/models"
 	"gin-blog/backend/utils"
 	"github.com/gin-gonic/gin"
 )
 
+// loadActiveUser 加载 token 对应的后台用户，用户已删除或被禁用时返回错误
+func loadActiveUser(userID uint) (*models.User, error) {
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		return nil, errors.New("Invalid token: user no longer exists")
+	}
+	if user.Disabled {
+		return nil, errors.New("Account has been disabled")
+	}
+	return &user, nil
+}
+
+// setUserContext 写入后台用户信息；userType 仍为 admin，表示非访客的后台账号
+func setUserContext(c *gin.Context, user *models.User) {
+	c.Set("userID", user.ID)
+	c.Set("username", user.Username)
+	c.Set("userType", "admin")
+	c.Set("userRole", user.Role)
+	c.Set("currentUser", user)
+}
+
+// RequirePermission 要求当前后台用户的角色拥有指定权限，需放在 AuthMiddleware 之后
+func RequirePermission(perm models.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !models.RoleHasPermission(c.GetString("userRole"), perm) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to perform this action"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
 func AuthMiddleware(allowGuests bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
@@ -33,9 +69,13 @@ func AuthMiddleware(allowGuests bool) gin.HandlerFunc {
 		}
 
 		if claims.UserID != 0 {
-			c.Set("userID", claims.UserID)
-			c.Set("username", claims.Username)
-			c.Set("userType", "admin")
+			user, err := loadActiveUser(claims.UserID)
+			if err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+				c.Abort()
+				return
+			}
+			setUserContext(c, user)
 		} else if claims.GuestUserID != 0 {
 			if !allowGuests {
 				c.JSON(http.StatusForbidden, gin.H{"error": "Guest users are not permitted for this action"})
@@ -51,6 +91,38 @@ func AuthMiddleware(allowGuests bool) gin.HandlerFunc {
 			c.Abort()
 			return
 		}
+		c.Set("tokenClaims", claims)
+		c.Next()
+	}
+}
+
+// OptionalAuthMiddleware 用于公开接口：携带有效 token 时写入用户信息，未携带或无效时按匿名访问继续处理
+func OptionalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		parts := strings.Split(c.GetHeader("Authorization"), " ")
+		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+			c.Next()
+			return
+		}
+
+		claims, err := utils.ValidateToken(parts[1])
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		if claims.UserID != 0 {
+			if user, err := loadActiveUser(claims.UserID); err == nil {
+				setUserContext(c, user)
+				c.Set("tokenClaims", claims)
+			}
+		} else if claims.GuestUserID != 0 {
+			c.Set("guestUserID", claims.GuestUserID)
+			c.Set("username", claims.Username)
+			c.Set("avatarURL", claims.AvatarURL)
+			c.Set("userType", "guest")
+			c.Set("tokenClaims", claims)
+		}
 		c.Next()
 	}
-}
\ No newline at end of file
+}
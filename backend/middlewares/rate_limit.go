@@ -0,0 +1,203 @@
+package middlewares
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gin-blog/backend/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Rate 令牌桶参数：每 Per 时间补充 Limit 个令牌，桶容量为 Burst（未设置时等于 Limit）
+type Rate struct {
+	Limit int
+	Per   time.Duration
+	Burst int
+}
+
+func (r Rate) capacity() int {
+	if r.Burst > 0 {
+		return r.Burst
+	}
+	return r.Limit
+}
+
+// interval 补充一个令牌所需的时间
+func (r Rate) interval() time.Duration {
+	return r.Per / time.Duration(r.Limit)
+}
+
+// RateLimitResult 一次取令牌的结果
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration // 被拒绝时距离下一个令牌可用的时间
+	ResetAfter time.Duration // 桶重新装满所需的时间
+}
+
+// RateLimitStore 令牌桶的存储后端。默认使用进程内存储，多实例部署时可以换成 Redis 等共享实现。
+type RateLimitStore interface {
+	// Take 从 key 对应的桶中取出一个令牌
+	Take(key string, rate Rate, now time.Time) (RateLimitResult, error)
+}
+
+// RateLimitBackend 限流中间件使用的存储后端，需在注册路由之前替换
+var RateLimitBackend RateLimitStore = NewMemoryRateLimitStore()
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time // 桶装满的时间，过后该记录可以清理
+}
+
+// MemoryRateLimitStore 基于内存的令牌桶存储，只在单进程内生效
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]*bucket)}
+}
+
+const rateLimitSweepInterval = time.Minute
+
+func (s *MemoryRateLimitStore) Take(key string, rate Rate, now time.Time) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 定期清理已经装满的桶，它们和不存在的桶等价
+	if now.Sub(s.lastSweep) > rateLimitSweepInterval {
+		for k, b := range s.buckets {
+			if !now.Before(b.full) {
+				delete(s.buckets, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	capacity := float64(rate.capacity())
+	interval := rate.interval()
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, updated: now}
+		s.buckets[key] = b
+	} else if elapsed := now.Sub(b.updated); elapsed > 0 {
+		b.tokens = math.Min(capacity, b.tokens+float64(elapsed)/float64(interval))
+		b.updated = now
+	}
+
+	result := RateLimitResult{Limit: rate.capacity()}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - b.tokens) * float64(interval))
+	}
+	result.Remaining = int(b.tokens)
+	result.ResetAfter = time.Duration((capacity - b.tokens) * float64(interval))
+	b.full = now.Add(result.ResetAfter)
+	return result, nil
+}
+
+// ParseRate 解析 "次数/时长" 格式的限流配置，例如 "30/1m"、"5/10s"，可以追加突发容量 "30/1m,burst=60"
+func ParseRate(value string) (Rate, error) {
+	var rate Rate
+	spec, burst, hasBurst := strings.Cut(strings.TrimSpace(value), ",")
+	limit, per, ok := strings.Cut(spec, "/")
+	if !ok {
+		return rate, fmt.Errorf("rate %q must look like <requests>/<duration>", value)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(limit))
+	if err != nil || n <= 0 {
+		return rate, fmt.Errorf("invalid request count in rate %q", value)
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(per))
+	if err != nil || d <= 0 {
+		return rate, fmt.Errorf("invalid duration in rate %q", value)
+	}
+	rate.Limit, rate.Per = n, d
+
+	if hasBurst {
+		name, size, _ := strings.Cut(strings.TrimSpace(burst), "=")
+		b, err := strconv.Atoi(strings.TrimSpace(size))
+		if strings.TrimSpace(name) != "burst" || err != nil || b <= 0 {
+			return rate, fmt.Errorf("invalid burst in rate %q", value)
+		}
+		rate.Burst = b
+	}
+	return rate, nil
+}
+
+// configuredRate 读取 RATE_LIMIT_<NAME> 对应的配置，设置为 off 或 0 时关闭该路由的限流
+func configuredRate(cfg config.RateLimitConfig, name string, fallback Rate) (Rate, bool) {
+	env := "RATE_LIMIT_" + strings.ToUpper(name)
+	value := cfg.Rates[strings.ToLower(name)]
+	switch strings.ToLower(value) {
+	case "":
+		return fallback, true
+	case "off", "0", "false":
+		return Rate{}, false
+	}
+	rate, err := ParseRate(value)
+	if err != nil {
+		log.Printf("Warning: %s: %v, using default %d/%s", env, err, fallback.Limit, fallback.Per)
+		return fallback, true
+	}
+	return rate, true
+}
+
+// rateLimitIdentity 优先按登录身份限流，匿名请求按来源 IP 限流，需放在认证中间件之后
+func rateLimitIdentity(c *gin.Context) string {
+	if userID, ok := c.Get("userID"); ok {
+		return fmt.Sprintf("admin:%v", userID)
+	}
+	if guestID, ok := c.Get("guestUserID"); ok {
+		return fmt.Sprintf("guest:%v", guestID)
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// RateLimit 按路由名称限流，配置来自 RATE_LIMIT_<NAME>，未设置时使用 fallback。
+// 设置 RATE_LIMIT_ENABLED=false 可以关闭全部限流。
+func RateLimit(cfg config.RateLimitConfig, name string, fallback Rate) gin.HandlerFunc {
+	rate, enabled := configuredRate(cfg, name, fallback)
+	if !cfg.Enabled || !enabled {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	return func(c *gin.Context) {
+		result, err := RateLimitBackend.Take(name+":"+rateLimitIdentity(c), rate, time.Now())
+		if err != nil {
+			// 存储后端故障时放行，避免限流组件导致接口不可用
+			log.Printf("Rate limit backend error for %s: %v", name, err)
+			c.Next()
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+		if !result.Allowed {
+			retryAfter := ceilSeconds(result.RetryAfter)
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": fmt.Sprintf("请求过于频繁，请 %d 秒后再试", retryAfter)})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gin-blog/backend/config"
+	"gin-blog/backend/database"
+	"gin-blog/backend/migrations"
+)
+
+const migrateUsage = `usage: server migrate <command>
+
+commands:
+  up                 apply all pending migrations
+  down [n|all]       roll back the last n migrations (default 1)
+  status             list migrations and whether they have been applied
+  create [-dir d] name
+                     generate a new migration file in d (default "migrations")`
+
+const migrationTemplate = `package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	register(&Migration{
+		Version: "%s",
+		Name:    "%s",
+		Up: func(tx *gorm.DB) error {
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			return nil
+		},
+	})
+}
+`
+
+var migrationNamePattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+// runMigrate 处理 `server migrate` 子命令
+func runMigrate(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	command, args := args[0], args[1:]
+	if command == "create" {
+		return createMigration(args)
+	}
+
+	database.ConnectDatabase(cfg.Database)
+	switch command {
+	case "up":
+		applied, err := migrations.Up(database.DB)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Applied %d migration(s).\n", applied)
+	case "down":
+		steps := 1
+		if len(args) > 0 {
+			if args[0] == "all" {
+				steps = len(migrations.All())
+			} else {
+				n, err := strconv.Atoi(args[0])
+				if err != nil || n <= 0 {
+					return fmt.Errorf("invalid number of steps %q", args[0])
+				}
+				steps = n
+			}
+		}
+		rolledBack, err := migrations.Down(database.DB, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Rolled back %d migration(s).\n", rolledBack)
+	case "status":
+		statuses, err := migrations.Status(database.DB)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS")
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != nil {
+				state = "applied " + status.AppliedAt.Local().Format(time.DateTime)
+			}
+			if status.Missing {
+				state += " (missing from code)"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", status.Version, status.Name, state)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown migrate command %q\n\n%s", command, migrateUsage)
+	}
+	return nil
+}
+
+// createMigration 生成以当前 UTC 时间为版本号的迁移文件
+func createMigration(args []string) error {
+	flags := flag.NewFlagSet("migrate create", flag.ContinueOnError)
+	dir := flags.String("dir", "migrations", "directory of the migrations package")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New(migrateUsage)
+	}
+
+	name := strings.Trim(migrationNamePattern.ReplaceAllString(strings.ToLower(flags.Arg(0)), "_"), "_")
+	if name == "" {
+		return fmt.Errorf("invalid migration name %q", flags.Arg(0))
+	}
+	version := time.Now().UTC().Format("20060102150405")
+	path := filepath.Join(*dir, version+"_"+name+".go")
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err := fmt.Fprintf(file, migrationTemplate, version, name); err != nil {
+		return err
+	}
+	fmt.Printf("Created %s\n", path)
+	return nil
+}
@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	"gin-blog/backend/migrations/initialschema"
+
+	"gorm.io/gorm"
+)
+
+// 初始迁移按引入版本化迁移时的 models 建表。已由 AutoMigrate 建好表的旧数据库执行时
+// 不会改动已有的表和数据，只补齐缺失的部分并记录版本。
+func init() {
+	register(&Migration{
+		Version: "20261018000000",
+		Name:    "initial_schema",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(initialschema.Models()...)
+		},
+		Down: func(tx *gorm.DB) error {
+			models := initialschema.Models()
+			tables := []interface{}{"post_tags", "post_media"}
+			for i := len(models) - 1; i >= 0; i-- {
+				tables = append(tables, models[i])
+			}
+			return tx.Migrator().DropTable(tables...)
+		},
+	})
+}
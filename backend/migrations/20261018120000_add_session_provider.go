@@ -0,0 +1,46 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 刷新令牌和一次性登录码记录访客登录使用的第三方登录提供方，签发的访问令牌据此填写 provider
+type refreshTokenProvider struct {
+	Provider string `gorm:"size:64"`
+}
+
+func (refreshTokenProvider) TableName() string {
+	return "refresh_tokens"
+}
+
+type oauthLoginCodeProvider struct {
+	Provider string `gorm:"size:64"`
+}
+
+func (oauthLoginCodeProvider) TableName() string {
+	return "oauth_login_codes"
+}
+
+func init() {
+	register(&Migration{
+		Version: "20261018120000",
+		Name:    "add_session_provider",
+		Up: func(tx *gorm.DB) error {
+			for _, model := range []interface{}{&refreshTokenProvider{}, &oauthLoginCodeProvider{}} {
+				if tx.Migrator().HasColumn(model, "Provider") {
+					continue
+				}
+				if err := tx.Migrator().AddColumn(model, "Provider"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, model := range []interface{}{&refreshTokenProvider{}, &oauthLoginCodeProvider{}} {
+				if err := tx.Migrator().DropColumn(model, "Provider"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,51 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 同一文章的版本号唯一。并发更新曾可能写入重复的版本号，建索引前把有重复的文章按 (version, id) 重新连续编号。
+type postRevisionVersion struct {
+	ID      uint
+	PostID  uint `gorm:"not null;index;uniqueIndex:idx_post_revision_version"`
+	Version int  `gorm:"not null;uniqueIndex:idx_post_revision_version"`
+}
+
+func (postRevisionVersion) TableName() string {
+	return "post_revisions"
+}
+
+func init() {
+	register(&Migration{
+		Version: "20261018130000",
+		Name:    "unique_post_revision_version",
+		Up: func(tx *gorm.DB) error {
+			var postIDs []uint
+			if err := tx.Model(&postRevisionVersion{}).Group("post_id, version").Having("COUNT(*) > 1").
+				Pluck("post_id", &postIDs).Error; err != nil {
+				return err
+			}
+			renumbered := map[uint]bool{}
+			for _, postID := range postIDs {
+				if renumbered[postID] {
+					continue
+				}
+				renumbered[postID] = true
+				var revisions []postRevisionVersion
+				if err := tx.Where("post_id = ?", postID).Order("version, id").Find(&revisions).Error; err != nil {
+					return err
+				}
+				for i, revision := range revisions {
+					if revision.Version == i+1 {
+						continue
+					}
+					if err := tx.Model(&revision).Update("version", i+1).Error; err != nil {
+						return err
+					}
+				}
+			}
+			return tx.Migrator().CreateIndex(&postRevisionVersion{}, "idx_post_revision_version")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropIndex(&postRevisionVersion{}, "idx_post_revision_version")
+		},
+	})
+}
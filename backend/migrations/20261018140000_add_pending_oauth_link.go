@@ -0,0 +1,47 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 关联登录方式改为在兑换一次性授权码时完成，授权码记录需要保存待关联的访客和提供方账号信息
+type oauthLoginCodePendingLink struct {
+	LinkGuestID uint
+	Subject     string
+	Username    string
+	Email       string
+	AvatarURL   string
+	AccessToken string
+}
+
+func (oauthLoginCodePendingLink) TableName() string {
+	return "oauth_login_codes"
+}
+
+var pendingLinkColumns = []string{"LinkGuestID", "Subject", "Username", "Email", "AvatarURL", "AccessToken"}
+
+func init() {
+	register(&Migration{
+		Version: "20261018140000",
+		Name:    "add_pending_oauth_link",
+		Up: func(tx *gorm.DB) error {
+			model := &oauthLoginCodePendingLink{}
+			for _, column := range pendingLinkColumns {
+				if tx.Migrator().HasColumn(model, column) {
+					continue
+				}
+				if err := tx.Migrator().AddColumn(model, column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			model := &oauthLoginCodePendingLink{}
+			for _, column := range pendingLinkColumns {
+				if err := tx.Migrator().DropColumn(model, column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
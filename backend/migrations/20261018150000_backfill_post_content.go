@@ -0,0 +1,86 @@
+package migrations
+
+import (
+	"encoding/json"
+
+	"gin-blog/backend/utils"
+
+	"gorm.io/gorm"
+)
+
+// 引入 Markdown 渲染缓存之前保存的文章没有 HTML、目录、字数和阅读时长，在这里一次性补齐
+type postRenderCache struct {
+	ID          uint
+	Content     string
+	ContentHTML string
+	TOC         string
+	WordCount   int
+	ReadingTime int
+}
+
+func (postRenderCache) TableName() string {
+	return "posts"
+}
+
+// tocEntry 与写入该迁移时 models.TOCEntry 的 JSON 结构一致
+type tocEntry struct {
+	Level    int        `json:"level"`
+	ID       string     `json:"id"`
+	Text     string     `json:"text"`
+	Children []tocEntry `json:"children,omitempty"`
+}
+
+func nestHeadings(headings []utils.Heading) []tocEntry {
+	var build func(i, parentLevel int) ([]tocEntry, int)
+	build = func(i, parentLevel int) ([]tocEntry, int) {
+		entries := []tocEntry{}
+		for i < len(headings) && headings[i].Level > parentLevel {
+			h := headings[i]
+			entry := tocEntry{Level: h.Level, ID: h.ID, Text: h.Text}
+			entry.Children, i = build(i+1, h.Level)
+			if len(entry.Children) == 0 {
+				entry.Children = nil
+			}
+			entries = append(entries, entry)
+		}
+		return entries, i
+	}
+	toc, _ := build(0, 0)
+	return toc
+}
+
+func init() {
+	register(&Migration{
+		Version: "20261018150000",
+		Name:    "backfill_post_content",
+		Up: func(tx *gorm.DB) error {
+			var posts []postRenderCache
+			if err := tx.Where("content_html IS NULL OR content_html = ''").Find(&posts).Error; err != nil {
+				return err
+			}
+			for _, post := range posts {
+				rendered, err := utils.RenderMarkdownDocument(post.Content)
+				if err != nil {
+					return err
+				}
+				toc, err := json.Marshal(nestHeadings(rendered.Headings))
+				if err != nil {
+					return err
+				}
+				if err := tx.Model(&postRenderCache{}).Where("id = ?", post.ID).Updates(map[string]interface{}{
+					"content_html": rendered.HTML,
+					"toc":          string(toc),
+					"word_count":   rendered.WordCount,
+					"reading_time": rendered.ReadingTimeMinutes(),
+				}).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		// 渲染缓存由正文生成，回滚时保留即可
+		Down: func(tx *gorm.DB) error {
+			return nil
+		},
+	})
+}
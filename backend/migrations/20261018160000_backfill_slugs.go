@@ -0,0 +1,77 @@
+package migrations
+
+import (
+	"fmt"
+	"strconv"
+
+	"gin-blog/backend/utils"
+
+	"gorm.io/gorm"
+)
+
+// 引入 slug 之前创建的文章、标签和分类没有 slug，按标题或名称生成，规则与写入该迁移时的控制器一致：
+// 纯数字会与 ID 混淆，加上类型前缀；重复时追加 -2、-3 等后缀。已软删除的记录同样处理。
+type slugSource struct {
+	ID     uint
+	Source string
+}
+
+// slugTables 表名、类型和生成 slug 的来源字段
+var slugTables = []struct {
+	table, kind, column string
+}{
+	{"posts", "post", "title"},
+	{"tags", "tag", "name"},
+	{"categories", "category", "name"},
+}
+
+func uniqueLegacySlug(tx *gorm.DB, table, kind, source string, excludeID uint) (string, error) {
+	base := utils.Slugify(source)
+	if base == "" {
+		base = kind
+	} else if _, err := strconv.ParseUint(base, 10, 32); err == nil {
+		base = kind + "-" + base
+	}
+
+	candidate := base
+	for i := 2; ; i++ {
+		var count int64
+		if err := tx.Table(table).Where("slug = ? AND id <> ?", candidate, excludeID).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+func init() {
+	register(&Migration{
+		Version: "20261018160000",
+		Name:    "backfill_slugs",
+		Up: func(tx *gorm.DB) error {
+			for _, t := range slugTables {
+				var rows []slugSource
+				if err := tx.Table(t.table).Select("id, " + t.column + " AS source").
+					Where("slug IS NULL OR slug = ''").Order("id").Scan(&rows).Error; err != nil {
+					return err
+				}
+				for _, row := range rows {
+					slug, err := uniqueLegacySlug(tx, t.table, t.kind, row.Source, row.ID)
+					if err != nil {
+						return err
+					}
+					if err := tx.Table(t.table).Where("id = ?", row.ID).Update("slug", slug).Error; err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		// 生成的 slug 已可能被外部链接引用，回滚时保留
+		Down: func(tx *gorm.DB) error {
+			return nil
+		},
+	})
+}
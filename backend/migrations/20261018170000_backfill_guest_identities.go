@@ -0,0 +1,72 @@
+package migrations
+
+import (
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 引入 GuestIdentity 之前通过 GitHub 登录的访客只在 guest_users.git_hub_id 中记录了 GitHub 账号，
+// 为他们补建 github 身份。回滚时删除仍未通过登录更新过的补建身份，git_hub_id 仍然保留。
+type legacyGitHubGuest struct {
+	ID        uint
+	GitHubID  *int64
+	Username  string
+	AvatarURL string
+}
+
+func (legacyGitHubGuest) TableName() string {
+	return "guest_users"
+}
+
+type backfilledGuestIdentity struct {
+	ID          uint
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	GuestUserID uint
+	Provider    string
+	Subject     string
+	Username    string
+	AvatarURL   string
+}
+
+func (backfilledGuestIdentity) TableName() string {
+	return "guest_identities"
+}
+
+func init() {
+	register(&Migration{
+		Version: "20261018170000",
+		Name:    "backfill_guest_identities",
+		Up: func(tx *gorm.DB) error {
+			var guests []legacyGitHubGuest
+			err := tx.Where("git_hub_id IS NOT NULL").
+				Where("id NOT IN (?)", tx.Model(&backfilledGuestIdentity{}).Where("provider = ?", "github").Select("guest_user_id")).
+				Find(&guests).Error
+			if err != nil {
+				return err
+			}
+			for _, guest := range guests {
+				identity := backfilledGuestIdentity{
+					GuestUserID: guest.ID,
+					Provider:    "github",
+					Subject:     strconv.FormatInt(*guest.GitHubID, 10),
+					Username:    guest.Username,
+					AvatarURL:   guest.AvatarURL,
+				}
+				if err := tx.Create(&identity).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			// 登录后身份会写入访问令牌，没有访问令牌的才是补建后未再使用的身份
+			return tx.Where("provider = ?", "github").
+				Where("access_token IS NULL OR access_token = ''").
+				Where("guest_user_id IN (?)", tx.Model(&legacyGitHubGuest{}).Where("git_hub_id IS NOT NULL").Select("id")).
+				Delete(&backfilledGuestIdentity{}).Error
+		},
+	})
+}
@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// 文章全文索引使用 SQLite FTS5 虚拟表，其他数据库使用 LIKE 检索，不需要建表。
+// 编译时未启用 FTS5（`-tags sqlite_fts5`）会跳过建表；之后启用 FTS5 需要回滚并重新执行该迁移。
+func sqliteHasFTS5(tx *gorm.DB) (bool, error) {
+	var enabled bool
+	err := tx.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&enabled).Error
+	return enabled, err
+}
+
+func init() {
+	register(&Migration{
+		Version: "20261018180000",
+		Name:    "create_posts_fts",
+		Up: func(tx *gorm.DB) error {
+			if tx.Dialector.Name() != "sqlite" {
+				return nil
+			}
+			enabled, err := sqliteHasFTS5(tx)
+			if err != nil {
+				return err
+			}
+			if !enabled {
+				log.Printf("Info: SQLite was built without FTS5, skipping the posts_fts full-text index.")
+				return nil
+			}
+			if err := tx.Exec("CREATE VIRTUAL TABLE IF NOT EXISTS posts_fts USING fts5(title, content, tokenize = 'trigram')").Error; err != nil {
+				return err
+			}
+			return tx.Exec(`INSERT INTO posts_fts(rowid, title, content)
+				SELECT id, title, content FROM posts
+				WHERE deleted_at IS NULL AND id NOT IN (SELECT rowid FROM posts_fts)`).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if tx.Dialector.Name() != "sqlite" {
+				return nil
+			}
+			return tx.Exec("DROP TABLE IF EXISTS posts_fts").Error
+		},
+	})
+}
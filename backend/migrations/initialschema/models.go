@@ -0,0 +1,212 @@
+// Package initialschema 冻结初始迁移时的表结构。
+//
+// 这里的类型是 models 在引入版本化迁移时的副本，之后修改 models 不会影响初始迁移；
+// 表结构的后续变化应当写成新的迁移，而不是修改这里。类型名与 models 保持一致，
+// 以便生成相同的表名、关联表列名和外键约束名。
+package initialschema
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type User struct {
+	gorm.Model
+	Username        string `gorm:"unique;not null"`
+	Password        string `gorm:"not null"`
+	Role            string `gorm:"not null;default:author;index"`
+	Disabled        bool   `gorm:"not null;default:false"`
+	InviteTokenHash string `gorm:"index"`
+	InviteExpiresAt *time.Time
+	TokenVersion    int `gorm:"not null;default:0"`
+	TOTPSecret      string
+	TOTPEnabled     bool  `gorm:"not null;default:false"`
+	TOTPLastStep    int64 `gorm:"not null;default:0"`
+}
+
+type Post struct {
+	gorm.Model
+	Title       string `gorm:"not null"`
+	Slug        string `gorm:"size:191;uniqueIndex"`
+	Content     string `gorm:"not null"`
+	ContentHTML string
+	TOC         []interface{} `gorm:"serializer:json"`
+	WordCount   int           `gorm:"default:0"`
+	ReadingTime int           `gorm:"default:0"`
+	UserID      uint
+	User        User   `gorm:"foreignKey:UserID"`
+	Tags        []*Tag `gorm:"many2many:post_tags;"`
+	CategoryID  *uint
+	Category    *Category `gorm:"foreignKey:CategoryID"`
+	LikesCount  int       `gorm:"default:0"`
+	ViewsCount  int64     `gorm:"default:0"`
+	Status      string    `gorm:"not null;default:published;index"`
+	PublishAt   *time.Time
+	PublishedAt *time.Time
+}
+
+type Tag struct {
+	gorm.Model
+	Name  string  `gorm:"unique;not null"`
+	Slug  string  `gorm:"size:191;uniqueIndex"`
+	Posts []*Post `gorm:"many2many:post_tags;"`
+}
+
+type Category struct {
+	gorm.Model
+	Name        string `gorm:"unique;not null"`
+	Slug        string `gorm:"size:191;uniqueIndex"`
+	Description string
+	SortOrder   int     `gorm:"default:0;index"`
+	ParentID    *uint   `gorm:"index"`
+	Posts       []*Post `gorm:"foreignKey:CategoryID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+}
+
+type GuestUser struct {
+	gorm.Model
+	GitHubID     *int64 `gorm:"unique"`
+	Username     string `gorm:"not null"`
+	AvatarURL    string
+	TokenVersion int `gorm:"not null;default:0"`
+	Identities   []GuestIdentity
+}
+
+type Comment struct {
+	gorm.Model
+	Content     string `gorm:"not null"`
+	PostID      uint   `gorm:"not null"`
+	Post        Post
+	GuestUserID uint      `gorm:"not null"`
+	GuestUser   GuestUser `gorm:"foreignKey:GuestUserID"`
+	ParentID    *uint     `gorm:"index"`
+	Status      string    `gorm:"not null;default:approved;index"`
+	EditedAt    *time.Time
+}
+
+type PostRevision struct {
+	gorm.Model
+	PostID         uint     `gorm:"not null;index"`
+	Version        int      `gorm:"not null"`
+	Title          string   `gorm:"not null"`
+	Content        string   `gorm:"not null"`
+	Tags           []string `gorm:"serializer:json"`
+	CategoryID     *uint
+	EditorID       uint
+	Editor         User `gorm:"foreignKey:EditorID"`
+	RestoredFromID *uint
+}
+
+type PostLike struct {
+	ID          uint `gorm:"primarykey"`
+	CreatedAt   time.Time
+	PostID      uint   `gorm:"not null;uniqueIndex:idx_post_likes_post_reader"`
+	ReaderKey   string `gorm:"not null;size:80;uniqueIndex:idx_post_likes_post_reader;index"`
+	GuestUserID *uint  `gorm:"index"`
+}
+
+type SlugRedirect struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	Kind      string `gorm:"not null;size:32;uniqueIndex:idx_slug_redirect_kind_slug"`
+	OldSlug   string `gorm:"not null;size:191;uniqueIndex:idx_slug_redirect_kind_slug"`
+	TargetID  uint   `gorm:"not null;index"`
+}
+
+type Media struct {
+	ID           uint `gorm:"primarykey"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	FileName     string `gorm:"not null"`
+	StorageKey   string `gorm:"not null;size:255;uniqueIndex"`
+	URL          string `gorm:"not null"`
+	ThumbnailKey string
+	ThumbnailURL string
+	ContentType  string `gorm:"not null;index"`
+	Size         int64  `gorm:"not null"`
+	SHA256       string `gorm:"not null;size:64;uniqueIndex"`
+	Width        int
+	Height       int
+	UploaderID   uint
+	Uploader     User    `gorm:"foreignKey:UploaderID"`
+	Posts        []*Post `gorm:"many2many:post_media;"`
+}
+
+type RefreshToken struct {
+	ID           uint `gorm:"primarykey"`
+	CreatedAt    time.Time
+	SubjectType  string    `gorm:"not null;index:idx_refresh_token_subject"`
+	SubjectID    uint      `gorm:"not null;index:idx_refresh_token_subject"`
+	TokenHash    string    `gorm:"size:64;uniqueIndex;not null"`
+	FamilyID     string    `gorm:"index;not null"`
+	ExpiresAt    time.Time `gorm:"index"`
+	RevokedAt    *time.Time
+	ReplacedByID *uint
+	UserAgent    string
+	IP           string
+}
+
+type RevokedToken struct {
+	JTI       string    `gorm:"primaryKey"`
+	ExpiresAt time.Time `gorm:"index"`
+}
+
+type OAuthLoginCode struct {
+	ID          uint `gorm:"primarykey"`
+	CreatedAt   time.Time
+	CodeHash    string `gorm:"size:64;uniqueIndex;not null"`
+	GuestUserID uint   `gorm:"not null"`
+	Redirect    string
+	ExpiresAt   time.Time `gorm:"index"`
+}
+
+func (OAuthLoginCode) TableName() string {
+	return "oauth_login_codes"
+}
+
+type GuestIdentity struct {
+	ID          uint `gorm:"primarykey"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	GuestUserID uint   `gorm:"not null;index"`
+	Provider    string `gorm:"not null;size:64;uniqueIndex:idx_guest_identity_subject"`
+	Subject     string `gorm:"not null;size:191;uniqueIndex:idx_guest_identity_subject"`
+	Username    string
+	Email       string
+	AvatarURL   string
+	AccessToken string
+}
+
+type RecoveryCode struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UserID    uint   `gorm:"not null;index"`
+	CodeHash  string `gorm:"not null;index"`
+	UsedAt    *time.Time
+}
+
+type LoginThrottle struct {
+	Key           string `gorm:"primaryKey;column:throttle_key"`
+	Failures      int    `gorm:"not null;default:0"`
+	LastFailureAt time.Time
+	LockedUntil   *time.Time
+}
+
+type AuthEvent struct {
+	ID        uint      `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"index"`
+	Event     string    `gorm:"not null;index"`
+	Username  string    `gorm:"index"`
+	IP        string
+	ActorID   *uint
+	Detail    string
+}
+
+// Models 按创建顺序返回全部表，被引用的表在前
+func Models() []interface{} {
+	return []interface{}{
+		&User{}, &Post{}, &Tag{}, &Category{}, &GuestUser{}, &Comment{}, &PostRevision{}, &PostLike{},
+		&SlugRedirect{}, &Media{}, &RefreshToken{}, &RevokedToken{}, &OAuthLoginCode{}, &GuestIdentity{},
+		&RecoveryCode{}, &LoginThrottle{}, &AuthEvent{},
+	}
+}
@@ -0,0 +1,191 @@
+// Package migrations 管理版本化的数据库迁移。
+//
+// 每个迁移是一个以时间戳为版本号的 Go 文件，在 init 中调用 register 注册 Up/Down 函数，
+// 可以用 `server migrate create <name>` 生成。已执行的版本记录在 schema_migrations 表中。
+package migrations
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration 一次表结构或数据变更，Up 和 Down 在同一个事务中执行并记录版本
+type Migration struct {
+	// Version 时间戳形式的版本号，例如 20261018000000，决定执行顺序
+	Version string
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// SchemaMigration 已执行的迁移记录
+type SchemaMigration struct {
+	Version   string    `gorm:"primaryKey;size:32"`
+	Name      string    `gorm:"not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// MigrationStatus 迁移的执行状态，AppliedAt 为空表示尚未执行
+type MigrationStatus struct {
+	Version   string
+	Name      string
+	AppliedAt *time.Time
+	// Missing 为 true 表示数据库中记录了该版本，但代码中已没有对应的迁移
+	Missing bool
+}
+
+var registry = map[string]*Migration{}
+
+// register 注册迁移，版本号重复时直接 panic，避免启动后才发现冲突
+func register(m *Migration) {
+	if m.Version == "" || m.Up == nil {
+		panic(fmt.Sprintf("migration %q must have a version and an Up function", m.Name))
+	}
+	if existing, ok := registry[m.Version]; ok {
+		panic(fmt.Sprintf("duplicate migration version %s: %s and %s", m.Version, existing.Name, m.Name))
+	}
+	registry[m.Version] = m
+}
+
+// All 按版本顺序返回全部迁移
+func All() []*Migration {
+	all := make([]*Migration, 0, len(registry))
+	for _, m := range registry {
+		all = append(all, m)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Version < all[j].Version })
+	return all
+}
+
+func ensureTable(db *gorm.DB) error {
+	return db.AutoMigrate(&SchemaMigration{})
+}
+
+func appliedVersions(db *gorm.DB) (map[string]SchemaMigration, error) {
+	if err := ensureTable(db); err != nil {
+		return nil, err
+	}
+	var records []SchemaMigration
+	if err := db.Find(&records).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[string]SchemaMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// Pending 返回尚未执行的迁移
+func Pending(db *gorm.DB) ([]*Migration, error) {
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+	var pending []*Migration
+	for _, m := range All() {
+		if _, ok := applied[m.Version]; !ok {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// Status 返回每个迁移的执行状态，包括数据库中存在但代码中缺失的版本
+func Status(db *gorm.DB) ([]MigrationStatus, error) {
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+	var statuses []MigrationStatus
+	for _, m := range All() {
+		status := MigrationStatus{Version: m.Version, Name: m.Name}
+		if record, ok := applied[m.Version]; ok {
+			appliedAt := record.AppliedAt
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	for version, record := range applied {
+		if _, ok := registry[version]; !ok {
+			appliedAt := record.AppliedAt
+			statuses = append(statuses, MigrationStatus{Version: version, Name: record.Name, AppliedAt: &appliedAt, Missing: true})
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// Up 依次执行全部未执行的迁移，返回执行的数量。某个迁移失败时停止，之前的迁移保持已执行状态。
+func Up(db *gorm.DB) (int, error) {
+	pending, err := Pending(db)
+	if err != nil {
+		return 0, err
+	}
+	for i, m := range pending {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return i, fmt.Errorf("migration %s_%s failed: %v", m.Version, m.Name, err)
+		}
+		log.Printf("Applied migration %s_%s", m.Version, m.Name)
+	}
+	return len(pending), nil
+}
+
+// Down 按版本倒序回滚最近执行的 steps 个迁移，返回回滚的数量
+func Down(db *gorm.DB, steps int) (int, error) {
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return 0, err
+	}
+	all := All()
+	rolledBack := 0
+	for i := len(all) - 1; i >= 0 && rolledBack < steps; i-- {
+		m := all[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		if m.Down == nil {
+			return rolledBack, fmt.Errorf("migration %s_%s cannot be rolled back", m.Version, m.Name)
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaMigration{}, "version = ?", m.Version).Error
+		})
+		if err != nil {
+			return rolledBack, fmt.Errorf("rollback of %s_%s failed: %v", m.Version, m.Name, err)
+		}
+		log.Printf("Rolled back migration %s_%s", m.Version, m.Name)
+		rolledBack++
+	}
+	return rolledBack, nil
+}
+
+// EnsureUpToDate 启动时检查未执行的迁移：生产环境拒绝启动，开发环境自动执行
+func EnsureUpToDate(db *gorm.DB, production bool) error {
+	pending, err := Pending(db)
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	if production {
+		return fmt.Errorf("%d pending migration(s), starting with %s_%s; run `migrate up` before starting the server",
+			len(pending), pending[0].Version, pending[0].Name)
+	}
+	log.Printf("Applying %d pending migration(s) in development mode.", len(pending))
+	_, err = Up(db)
+	return err
+}
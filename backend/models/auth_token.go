@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// 令牌所属主体的类型，与 JWT 中区分后台用户和访客的方式一致
+const (
+	SubjectAdmin = "admin"
+	SubjectGuest = "guest"
+)
+
+// RefreshToken 刷新令牌，数据库中只保存 SHA-256 摘要。
+// 每次刷新都会签发新令牌并吊销旧令牌，同一登录会话中轮换出的令牌共享 FamilyID，
+// 已吊销的令牌被再次使用时视为泄露，整个会话一并吊销。
+type RefreshToken struct {
+	ID           uint       `gorm:"primarykey" json:"id"`
+	CreatedAt    time.Time  `json:"created_at"`
+	SubjectType  string     `gorm:"not null;index:idx_refresh_token_subject" json:"subject_type"`
+	SubjectID    uint       `gorm:"not null;index:idx_refresh_token_subject" json:"subject_id"`
+	TokenHash    string     `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	FamilyID     string     `gorm:"index;not null" json:"family_id"`
+	Provider     string     `gorm:"size:64" json:"provider,omitempty"` // 访客登录使用的第三方登录提供方
+	ExpiresAt    time.Time  `gorm:"index" json:"expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	ReplacedByID *uint      `json:"replaced_by_id,omitempty"`
+	UserAgent    string     `json:"user_agent"`
+	IP           string     `json:"ip"`
+}
+
+// RevokedToken 已注销但尚未过期的访问令牌（按 jti 记录），过期后即可清理
+type RevokedToken struct {
+	JTI       string    `gorm:"primaryKey" json:"jti"`
+	ExpiresAt time.Time `gorm:"index" json:"expires_at"`
+}
@@ -4,6 +4,12 @@ import "gorm.io/gorm"
 
 type Category struct {
 	gorm.Model
-	Name  string  `gorm:"unique;not null" json:"name"`
-	Posts []*Post `gorm:"foreignKey:CategoryID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"-"`
-}
\ No newline at end of file
+	Name        string      `gorm:"unique;not null" json:"name"`
+	Slug        string      `gorm:"size:191;uniqueIndex" json:"slug"`
+	Description string      `json:"description"`
+	SortOrder   int         `gorm:"default:0;index" json:"sort_order"` // 越小越靠前，相同时按名称排序
+	ParentID    *uint       `gorm:"index" json:"parent_id"`
+	Posts       []*Post     `gorm:"foreignKey:CategoryID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"-"`
+	PostCount   int64       `gorm:"-" json:"post_count"`         // 直接属于该分类的可见文章数，按请求计算
+	Children    []*Category `gorm:"-" json:"children,omitempty"` // 仅在树形列表中返回
+}
@@ -1,12 +1,38 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 评论审核状态
+const (
+	CommentStatusPending  = "pending"
+	CommentStatusApproved = "approved"
+	CommentStatusRejected = "rejected"
+	CommentStatusSpam     = "spam"
+)
+
+// IsValidCommentStatus 判断是否为合法的评论审核状态
+func IsValidCommentStatus(status string) bool {
+	switch status {
+	case CommentStatusPending, CommentStatusApproved, CommentStatusRejected, CommentStatusSpam:
+		return true
+	}
+	return false
+}
 
 type Comment struct {
 	gorm.Model
-	Content     string `gorm:"not null" json:"content"`
-	PostID      uint   `gorm:"not null" json:"post_id"`
-	Post        Post   `json:"-"`
-	GuestUserID uint   `gorm:"not null" json:"guest_user_id"`
-	GuestUser   GuestUser `gorm:"foreignKey:GuestUserID" json:"guest_user"`
-}
\ No newline at end of file
+	Content     string     `gorm:"not null" json:"content"`
+	PostID      uint       `gorm:"not null" json:"post_id"`
+	Post        Post       `json:"-"`
+	GuestUserID uint       `gorm:"not null" json:"guest_user_id"`
+	GuestUser   GuestUser  `gorm:"foreignKey:GuestUserID" json:"guest_user"`
+	ParentID    *uint      `gorm:"index" json:"parent_id,omitempty"` // 回复的评论ID，顶层评论为空
+	Status      string     `gorm:"not null;default:approved;index" json:"status"`
+	EditedAt    *time.Time `json:"edited_at,omitempty"`
+	Replies     []*Comment `gorm:"-" json:"replies"` // 由接口按需组装的回复树，不落库
+	Deleted     bool       `gorm:"-" json:"deleted"` // 已删除但仍有回复的评论以占位形式返回
+}
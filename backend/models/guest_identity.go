@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// GuestIdentity 访客在某个第三方登录提供方处的账号，同一访客可以关联多个提供方
+type GuestIdentity struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	GuestUserID uint      `gorm:"not null;index" json:"guest_user_id"`
+	Provider    string    `gorm:"not null;size:64;uniqueIndex:idx_guest_identity_subject" json:"provider"`
+	Subject     string    `gorm:"not null;size:191;uniqueIndex:idx_guest_identity_subject" json:"-"`
+	Username    string    `json:"username"`
+	Email       string    `json:"-"`
+	AvatarURL   string    `json:"avatar_url"`
+	AccessToken string    `json:"-"`
+}
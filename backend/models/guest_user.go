@@ -4,8 +4,10 @@ import "gorm.io/gorm"
 
 type GuestUser struct {
 	gorm.Model
-	GitHubID    int64  `gorm:"unique;not null"`
-	Username    string `gorm:"not null"`
-	AvatarURL   string
-	AccessToken string `json:"-"`
-}
\ No newline at end of file
+	// GitHubID 仅保留给引入 GuestIdentity 之前创建的访客，由 backfill_guest_identities 迁移补建为 github 身份
+	GitHubID     *int64 `gorm:"unique" json:"-"`
+	Username     string `gorm:"not null"`
+	AvatarURL    string
+	TokenVersion int             `gorm:"not null;default:0" json:"-"`
+	Identities   []GuestIdentity `json:"identities,omitempty"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// LoginThrottle 登录失败计数，Key 形如 "user:<用户名>" 或 "ip:<地址>"
+type LoginThrottle struct {
+	Key           string     `gorm:"primaryKey;column:throttle_key" json:"key"`
+	Failures      int        `gorm:"not null;default:0" json:"failures"`
+	LastFailureAt time.Time  `json:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until,omitempty"`
+}
+
+// 安全审计事件类型
+const (
+	AuthEventLockout = "login_lockout"
+	AuthEventUnlock  = "account_unlock"
+)
+
+// AuthEvent 账号安全相关的审计记录，例如登录锁定和管理员解锁
+type AuthEvent struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+	Event     string    `gorm:"not null;index" json:"event"`
+	Username  string    `gorm:"index" json:"username"`
+	IP        string    `json:"ip"`
+	ActorID   *uint     `json:"actor_id,omitempty"`
+	Detail    string    `json:"detail"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Media 媒体库中的一个上传文件，相同内容（SHA-256 相同）只保存一份
+type Media struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	FileName     string    `gorm:"not null" json:"file_name"` // 上传时的原始文件名
+	StorageKey   string    `gorm:"not null;size:255;uniqueIndex" json:"-"`
+	URL          string    `gorm:"not null" json:"url"`
+	ThumbnailKey string    `json:"-"`
+	ThumbnailURL string    `json:"thumbnail_url,omitempty"`
+	ContentType  string    `gorm:"not null;index" json:"content_type"`
+	Size         int64     `gorm:"not null" json:"size"`
+	SHA256       string    `gorm:"not null;size:64;uniqueIndex" json:"sha256"`
+	Width        int       `json:"width,omitempty"`
+	Height       int       `json:"height,omitempty"`
+	UploaderID   uint      `json:"uploader_id"`
+	Uploader     User      `gorm:"foreignKey:UploaderID" json:"-"`
+	Posts        []*Post   `gorm:"many2many:post_media;" json:"-"`
+	PostIDs      []uint    `gorm:"-" json:"post_ids"` // 引用该文件的文章，按请求计算
+}
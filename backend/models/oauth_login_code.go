@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// OAuthLoginCode 第三方登录成功后发给前端的一次性授权码，前端用它换取 JWT，
+// 避免 token 出现在重定向地址中。数据库只保存授权码的 SHA-256。
+//
+// 关联登录方式时授权码同时记录待关联的提供方账号：GuestUserID 为 0，LinkGuestID 为发起关联的访客，
+// 只有该访客本人携带访问令牌兑换授权码时才会写入 GuestIdentity。
+type OAuthLoginCode struct {
+	ID          uint `gorm:"primarykey"`
+	CreatedAt   time.Time
+	CodeHash    string `gorm:"size:64;uniqueIndex;not null"`
+	GuestUserID uint   `gorm:"not null"`
+	Provider    string `gorm:"size:64"`
+	Redirect    string
+	ExpiresAt   time.Time `gorm:"index"`
+
+	LinkGuestID uint
+	Subject     string
+	Username    string
+	Email       string
+	AvatarURL   string
+	AccessToken string
+}
+
+func (OAuthLoginCode) TableName() string {
+	return "oauth_login_codes"
+}
@@ -1,15 +1,62 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 文章状态
+const (
+	PostStatusDraft     = "draft"
+	PostStatusPublished = "published"
+	PostStatusScheduled = "scheduled"
+	PostStatusArchived  = "archived"
+)
+
+// IsValidPostStatus 判断是否为合法的文章状态
+func IsValidPostStatus(status string) bool {
+	switch status {
+	case PostStatusDraft, PostStatusPublished, PostStatusScheduled, PostStatusArchived:
+		return true
+	}
+	return false
+}
+
+// TOCEntry 文章目录中的一项，子标题嵌套在 Children 中
+type TOCEntry struct {
+	Level    int        `json:"level"`
+	ID       string     `json:"id"`
+	Text     string     `json:"text"`
+	Children []TOCEntry `json:"children,omitempty"`
+}
 
 type Post struct {
 	gorm.Model
-	Title      string    `gorm:"not null" json:"title"`
-	Content    string    `gorm:"not null" json:"content"`
-	UserID     uint      `json:"user_id"`
-	User       User      `gorm:"foreignKey:UserID" json:"User"`
-	Tags       []*Tag    `gorm:"many2many:post_tags;" json:"tags"`
-	CategoryID *uint     `json:"category_id,omitempty"`
-	Category   *Category `gorm:"foreignKey:CategoryID" json:"Category,omitempty"`
-	LikesCount int       `gorm:"default:0" json:"likes_count"`
-}
\ No newline at end of file
+	Title       string     `gorm:"not null" json:"title"`
+	Slug        string     `gorm:"size:191;uniqueIndex" json:"slug"`
+	Content     string     `gorm:"not null" json:"content"`
+	ContentHTML string     `json:"content_html"` // 由 Content 渲染并过滤后的 HTML，随文章保存时更新
+	TOC         []TOCEntry `gorm:"serializer:json" json:"toc"`
+	WordCount   int        `gorm:"default:0" json:"word_count"`
+	ReadingTime int        `gorm:"default:0" json:"reading_time_minutes"`
+	UserID      uint       `json:"user_id"`
+	User        User       `gorm:"foreignKey:UserID" json:"User"`
+	Tags        []*Tag     `gorm:"many2many:post_tags;" json:"tags"`
+	CategoryID  *uint      `json:"category_id,omitempty"`
+	Category    *Category  `gorm:"foreignKey:CategoryID" json:"Category,omitempty"`
+	LikesCount  int        `gorm:"default:0" json:"likes_count"`
+	ViewsCount  int64      `gorm:"default:0" json:"views_count"`
+	Status      string     `gorm:"not null;default:published;index" json:"status"`
+	PublishAt   *time.Time `json:"publish_at,omitempty"`   // 定时发布时间，仅 scheduled 状态使用
+	PublishedAt *time.Time `json:"published_at,omitempty"` // 实际发布时间
+	LikedByMe   bool       `gorm:"-" json:"liked_by_me"`   // 当前读者是否已点赞，按请求计算，不落库
+}
+
+// IsPublic 判断文章当前是否对公众可见
+func (post *Post) IsPublic(now time.Time) bool {
+	if post.Status == PostStatusPublished {
+		return true
+	}
+	return post.Status == PostStatusScheduled && post.PublishAt != nil && !post.PublishAt.After(now)
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// PostLike 记录某位读者对文章的点赞，同一读者对同一文章只能点赞一次。
+// ReaderKey 为 "guest:<id>"、"user:<id>" 或匿名访客指纹的哈希 "anon:<sha256>"。
+type PostLike struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	PostID      uint      `gorm:"not null;uniqueIndex:idx_post_likes_post_reader" json:"post_id"`
+	ReaderKey   string    `gorm:"not null;size:80;uniqueIndex:idx_post_likes_post_reader;index" json:"-"`
+	GuestUserID *uint     `gorm:"index" json:"guest_user_id,omitempty"`
+}
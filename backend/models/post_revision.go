@@ -0,0 +1,17 @@
+package models
+
+import "gorm.io/gorm"
+
+// PostRevision 文章每次创建或更新后的快照
+type PostRevision struct {
+	gorm.Model
+	PostID         uint     `gorm:"not null;index;uniqueIndex:idx_post_revision_version" json:"post_id"`
+	Version        int      `gorm:"not null;uniqueIndex:idx_post_revision_version" json:"version"`
+	Title          string   `gorm:"not null" json:"title"`
+	Content        string   `gorm:"not null" json:"content"`
+	Tags           []string `gorm:"serializer:json" json:"tags"`
+	CategoryID     *uint    `json:"category_id,omitempty"`
+	EditorID       uint     `json:"editor_id"`
+	Editor         User     `gorm:"foreignKey:EditorID" json:"editor"`
+	RestoredFromID *uint    `json:"restored_from_id,omitempty"` // 通过恢复旧版本产生时，记录来源版本
+}
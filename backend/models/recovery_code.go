@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// RecoveryCode 两步验证的一次性恢复码，只保存 SHA-256 摘要，使用后记录 UsedAt
+type RecoveryCode struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UserID    uint   `gorm:"not null;index"`
+	CodeHash  string `gorm:"not null;index"`
+	UsedAt    *time.Time
+}
@@ -0,0 +1,51 @@
+package models
+
+// 用户角色
+const (
+	RoleOwner       = "owner"       // 站点所有者，拥有全部权限，包括管理用户
+	RoleEditor      = "editor"      // 编辑，可以修改、发布任何人的文章并管理分类、标签和评论
+	RoleAuthor      = "author"      // 作者，可以发布和管理自己的文章
+	RoleContributor = "contributor" // 投稿者，只能提交和修改自己的草稿
+)
+
+// Permission 角色可执行的操作
+type Permission string
+
+const (
+	PermPublishPost      Permission = "post:publish"    // 发布或定时发布文章
+	PermEditAnyPost      Permission = "post:edit_any"   // 修改和删除他人的文章
+	PermManageTaxonomy   Permission = "taxonomy:manage" // 管理分类和标签
+	PermModerateComments Permission = "comment:moderate"
+	PermUploadMedia      Permission = "media:upload"
+	PermManageMedia      Permission = "media:manage" // 删除媒体库中的文件
+	PermManageUsers      Permission = "user:manage"
+)
+
+var rolePermissions = map[string][]Permission{
+	RoleOwner: {
+		PermPublishPost, PermEditAnyPost, PermManageTaxonomy, PermModerateComments,
+		PermUploadMedia, PermManageMedia, PermManageUsers,
+	},
+	RoleEditor: {
+		PermPublishPost, PermEditAnyPost, PermManageTaxonomy, PermModerateComments,
+		PermUploadMedia, PermManageMedia,
+	},
+	RoleAuthor:      {PermPublishPost, PermUploadMedia},
+	RoleContributor: {PermUploadMedia},
+}
+
+// IsValidRole 判断是否为合法的用户角色
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// RoleHasPermission 判断角色是否拥有指定权限
+func RoleHasPermission(role string, perm Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
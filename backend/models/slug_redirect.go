@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// slug 所属的资源类型
+const (
+	SlugKindPost     = "post"
+	SlugKindTag      = "tag"
+	SlugKindCategory = "category"
+)
+
+// SlugRedirect 记录资源改名前使用过的 slug，旧链接据此跳转到当前资源
+type SlugRedirect struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Kind      string    `gorm:"not null;size:32;uniqueIndex:idx_slug_redirect_kind_slug" json:"kind"`
+	OldSlug   string    `gorm:"not null;size:191;uniqueIndex:idx_slug_redirect_kind_slug" json:"old_slug"`
+	TargetID  uint      `gorm:"not null;index" json:"target_id"`
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
@@ -9,6 +11,27 @@ type User struct {
 	gorm.Model
 	Username string `gorm:"unique;not null" json:"username"`
 	Password string `gorm:"not null" json:"-"` // json:"-" to not expose password
+	Role     string `gorm:"not null;default:author;index" json:"role"`
+	Disabled bool   `gorm:"not null;default:false" json:"disabled"`
+	// 邀请尚未接受时 Password 为空，InviteTokenHash 保存邀请码的 SHA-256
+	InviteTokenHash string     `gorm:"index" json:"-"`
+	InviteExpiresAt *time.Time `json:"invite_expires_at,omitempty"`
+	// TokenVersion 递增后该用户此前签发的所有访问令牌失效
+	TokenVersion int `gorm:"not null;default:0" json:"-"`
+	// 两步验证：TOTPSecret 在启用前保存待确认的密钥，TOTPLastStep 记录最近使用的时间步以防验证码重放
+	TOTPSecret   string `json:"-"`
+	TOTPEnabled  bool   `gorm:"not null;default:false" json:"two_factor_enabled"`
+	TOTPLastStep int64  `gorm:"not null;default:0" json:"-"`
+}
+
+// IsPending 判断用户是否为尚未接受邀请、还不能登录的账号
+func (user *User) IsPending() bool {
+	return user.Password == ""
+}
+
+// Can 判断用户当前是否拥有指定权限，被禁用的用户没有任何权限
+func (user *User) Can(perm Permission) bool {
+	return user != nil && !user.Disabled && RoleHasPermission(user.Role, perm)
 }
 
 // HashPassword hashes the user's password before saving
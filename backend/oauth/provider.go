@@ -0,0 +1,162 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"gin-blog/backend/config"
+
+	"golang.org/x/oauth2"
+)
+
+// Profile 第三方账号的基本资料，Subject 是该账号在提供方处不变的唯一标识
+type Profile struct {
+	Subject   string
+	Username  string
+	Email     string
+	AvatarURL string
+}
+
+// Provider 第三方登录提供方。授权地址和换取令牌遵循 OAuth 2.0 授权码流程，
+// 资料接口各家不同，由具体实现解析。
+type Provider interface {
+	// Name 提供方名称，用于路由 /api/auth/:provider/login 和身份记录
+	Name() string
+	// AuthCodeURL 返回跳转到提供方授权页的地址
+	AuthCodeURL(state string, opts ...oauth2.AuthCodeOption) string
+	// Exchange 用授权码换取访问令牌
+	Exchange(ctx context.Context, code string, opts ...oauth2.AuthCodeOption) (*oauth2.Token, error)
+	// FetchProfile 使用访问令牌获取用户资料
+	FetchProfile(ctx context.Context, token *oauth2.Token) (*Profile, error)
+}
+
+// oauth2Provider 基于 oauth2.Config 的通用实现，具体提供方只需给出资料接口地址和解析方式
+type oauth2Provider struct {
+	name         string
+	config       *oauth2.Config
+	profileURL   string
+	parseProfile func(body []byte) (*Profile, error)
+}
+
+func (p *oauth2Provider) Name() string {
+	return p.name
+}
+
+func (p *oauth2Provider) AuthCodeURL(state string, opts ...oauth2.AuthCodeOption) string {
+	return p.config.AuthCodeURL(state, opts...)
+}
+
+func (p *oauth2Provider) Exchange(ctx context.Context, code string, opts ...oauth2.AuthCodeOption) (*oauth2.Token, error) {
+	return p.config.Exchange(ctx, code, opts...)
+}
+
+func (p *oauth2Provider) FetchProfile(ctx context.Context, token *oauth2.Token) (*Profile, error) {
+	body, err := getJSON(ctx, p.config.Client(ctx, token), p.profileURL)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := p.parseProfile(body)
+	if err != nil {
+		return nil, err
+	}
+	if profile.Subject == "" {
+		return nil, fmt.Errorf("%s profile has no user id", p.name)
+	}
+	if profile.Username == "" {
+		profile.Username = strings.SplitN(profile.Email, "@", 2)[0]
+	}
+	return profile, nil
+}
+
+func getJSON(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s returned %s", url, resp.Status)
+	}
+	return body, nil
+}
+
+var providers = map[string]Provider{}
+
+// Register 注册一个提供方，同名的提供方会被替换
+func Register(p Provider) {
+	providers[p.Name()] = p
+}
+
+// Get 按名称查找已启用的提供方
+func Get(name string) (Provider, bool) {
+	p, ok := providers[name]
+	return p, ok
+}
+
+// Names 返回所有已启用提供方的名称
+func Names() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func oauth2Config(p config.OAuthProviderConfig) oauth2.Config {
+	return oauth2.Config{
+		ClientID:     p.ClientID,
+		ClientSecret: p.ClientSecret,
+		RedirectURL:  p.RedirectURL,
+	}
+}
+
+// SetupProviders 根据配置启用第三方登录提供方，未设置 ClientID 的提供方不启用
+func SetupProviders(cfg config.OAuthConfig) {
+	if cfg.GitHub.Enabled() {
+		Register(NewGitHub(oauth2Config(cfg.GitHub), ""))
+	}
+	if cfg.GitLab.Enabled() {
+		Register(NewGitLab(oauth2Config(cfg.GitLab), cfg.GitLabURL))
+	}
+	if cfg.Google.Enabled() {
+		Register(NewGoogle(oauth2Config(cfg.Google)))
+	}
+	if cfg.OIDC.Enabled() {
+		p, err := NewOIDC(context.Background(), cfg.OIDCProviderName, oauth2Config(cfg.OIDC), cfg.OIDCIssuerURL)
+		if err != nil {
+			log.Printf("Warning: OIDC login disabled: %v", err)
+		} else {
+			Register(p)
+		}
+	}
+
+	if len(providers) == 0 {
+		log.Println("No OAuth login providers configured, guest login is disabled.")
+		return
+	}
+	log.Printf("OAuth login providers enabled: %s", strings.Join(Names(), ", "))
+}
+
+func unmarshalProfile(body []byte, v interface{}) error {
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to parse profile: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/endpoints"
+	"golang.org/x/oauth2/github"
+)
+
+// NewGitHub 创建 GitHub 提供方，apiURL 为空时使用 https://api.github.com
+func NewGitHub(config oauth2.Config, apiURL string) Provider {
+	if config.Endpoint.AuthURL == "" {
+		config.Endpoint = github.Endpoint
+	}
+	if apiURL == "" {
+		apiURL = "https://api.github.com"
+	}
+	config.Scopes = []string{"read:user", "user:email"}
+	return &oauth2Provider{
+		name:       "github",
+		config:     &config,
+		profileURL: strings.TrimRight(apiURL, "/") + "/user",
+		parseProfile: func(body []byte) (*Profile, error) {
+			var user struct {
+				ID        int64  `json:"id"`
+				Login     string `json:"login"`
+				AvatarURL string `json:"avatar_url"`
+				Email     string `json:"email"`
+			}
+			if err := unmarshalProfile(body, &user); err != nil {
+				return nil, err
+			}
+			profile := &Profile{Username: user.Login, Email: user.Email, AvatarURL: user.AvatarURL}
+			if user.ID != 0 {
+				profile.Subject = strconv.FormatInt(user.ID, 10)
+			}
+			return profile, nil
+		},
+	}
+}
+
+// NewGitLab 创建 GitLab 提供方，baseURL 为空时使用 gitlab.com，也可以指向自建实例
+func NewGitLab(config oauth2.Config, baseURL string) Provider {
+	if baseURL == "" {
+		baseURL = "https://gitlab.com"
+	}
+	baseURL = strings.TrimRight(baseURL, "/")
+	config.Endpoint = oauth2.Endpoint{
+		AuthURL:  baseURL + "/oauth/authorize",
+		TokenURL: baseURL + "/oauth/token",
+	}
+	config.Scopes = []string{"read_user"}
+	return &oauth2Provider{
+		name:       "gitlab",
+		config:     &config,
+		profileURL: baseURL + "/api/v4/user",
+		parseProfile: func(body []byte) (*Profile, error) {
+			var user struct {
+				ID        int64  `json:"id"`
+				Username  string `json:"username"`
+				Email     string `json:"email"`
+				AvatarURL string `json:"avatar_url"`
+			}
+			if err := unmarshalProfile(body, &user); err != nil {
+				return nil, err
+			}
+			profile := &Profile{Username: user.Username, Email: user.Email, AvatarURL: user.AvatarURL}
+			if user.ID != 0 {
+				profile.Subject = strconv.FormatInt(user.ID, 10)
+			}
+			return profile, nil
+		},
+	}
+}
+
+// NewGoogle 创建 Google 提供方，资料取自 OpenID Connect 的 userinfo 接口
+func NewGoogle(config oauth2.Config) Provider {
+	config.Endpoint = endpoints.Google
+	config.Scopes = []string{"openid", "email", "profile"}
+	return &oauth2Provider{
+		name:         "google",
+		config:       &config,
+		profileURL:   "https://openidconnect.googleapis.com/v1/userinfo",
+		parseProfile: parseOIDCUserInfo,
+	}
+}
+
+// NewOIDC 通过 issuer 的 /.well-known/openid-configuration 发现端点，创建通用的 OpenID Connect 提供方
+func NewOIDC(ctx context.Context, name string, config oauth2.Config, issuerURL string) (Provider, error) {
+	if issuerURL == "" {
+		return nil, fmt.Errorf("OIDC_ISSUER_URL is not set")
+	}
+	body, err := getJSON(ctx, http.DefaultClient, strings.TrimRight(issuerURL, "/")+"/.well-known/openid-configuration")
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover OIDC configuration: %v", err)
+	}
+	var discovery struct {
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	}
+	if err := json.Unmarshal(body, &discovery); err != nil {
+		return nil, fmt.Errorf("failed to parse OIDC configuration: %v", err)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.UserinfoEndpoint == "" {
+		return nil, fmt.Errorf("OIDC configuration of %s is missing required endpoints", issuerURL)
+	}
+
+	config.Endpoint = oauth2.Endpoint{
+		AuthURL:  discovery.AuthorizationEndpoint,
+		TokenURL: discovery.TokenEndpoint,
+	}
+	config.Scopes = []string{"openid", "email", "profile"}
+	return &oauth2Provider{
+		name:         name,
+		config:       &config,
+		profileURL:   discovery.UserinfoEndpoint,
+		parseProfile: parseOIDCUserInfo,
+	}, nil
+}
+
+func parseOIDCUserInfo(body []byte) (*Profile, error) {
+	var info struct {
+		Sub               string `json:"sub"`
+		PreferredUsername string `json:"preferred_username"`
+		Name              string `json:"name"`
+		Email             string `json:"email"`
+		Picture           string `json:"picture"`
+	}
+	if err := unmarshalProfile(body, &info); err != nil {
+		return nil, err
+	}
+	username := info.PreferredUsername
+	if username == "" {
+		username = info.Name
+	}
+	return &Profile{Subject: info.Sub, Username: username, Email: info.Email, AvatarURL: info.Picture}, nil
+}
@@ -0,0 +1,356 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+const (
+	testClientID     = "client-id"
+	testClientSecret = "client-secret"
+	testRedirectURL  = "http://blog.test/api/auth/callback"
+	testAuthCode     = "auth-code"
+	testAccessToken  = "access-token"
+)
+
+// fakeIdP 模拟提供方的令牌接口、资料接口和 OIDC 发现文档
+type fakeIdP struct {
+	*httptest.Server
+	t *testing.T
+	// verifier 令牌接口期望收到的 PKCE code_verifier
+	verifier string
+	// profiles 资料接口路径到响应内容的映射
+	profiles map[string]string
+	// discovery 为 nil 时返回指向本服务的完整发现文档
+	discovery map[string]string
+}
+
+func newFakeIdP(t *testing.T) *fakeIdP {
+	idp := &fakeIdP{t: t, profiles: map[string]string{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", idp.handleToken)
+	mux.HandleFunc("/.well-known/openid-configuration", idp.handleDiscovery)
+	mux.HandleFunc("/", idp.handleProfile)
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+func (idp *fakeIdP) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != testClientID || clientSecret != testClientSecret {
+		writeTokenError(w, http.StatusUnauthorized, "invalid_client")
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" || r.PostForm.Get("code") != testAuthCode ||
+		r.PostForm.Get("redirect_uri") != testRedirectURL {
+		writeTokenError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+	if r.PostForm.Get("code_verifier") != idp.verifier {
+		writeTokenError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": testAccessToken,
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+	})
+}
+
+func writeTokenError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
+
+func (idp *fakeIdP) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	discovery := idp.discovery
+	if discovery == nil {
+		discovery = map[string]string{
+			"issuer":                 idp.URL,
+			"authorization_endpoint": idp.URL + "/authorize",
+			"token_endpoint":         idp.URL + "/token",
+			"userinfo_endpoint":      idp.URL + "/userinfo",
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(discovery)
+}
+
+func (idp *fakeIdP) handleProfile(w http.ResponseWriter, r *http.Request) {
+	body, ok := idp.profiles[r.URL.Path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer "+testAccessToken {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(body))
+}
+
+// rewriteTransport 把发往真实提供方的请求转发到测试服务器，用于端点固定的 Google
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (rt rewriteTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.URL.Scheme, r.URL.Host = rt.target.Scheme, rt.target.Host
+	r.Host = rt.target.Host
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func testConfig() oauth2.Config {
+	return oauth2.Config{ClientID: testClientID, ClientSecret: testClientSecret, RedirectURL: testRedirectURL}
+}
+
+// newTestProvider 创建指向 fakeIdP 的提供方，返回的 ctx 用于 Google 的请求转发
+func newTestProvider(t *testing.T, idp *fakeIdP, name string) (Provider, context.Context) {
+	ctx := context.Background()
+	switch name {
+	case "github":
+		config := testConfig()
+		config.Endpoint = oauth2.Endpoint{AuthURL: idp.URL + "/login/oauth/authorize", TokenURL: idp.URL + "/token"}
+		return NewGitHub(config, idp.URL), ctx
+	case "gitlab":
+		// GitLab 的令牌接口是 /oauth/token
+		idp.Config.Handler.(*http.ServeMux).HandleFunc("/oauth/token", idp.handleToken)
+		return NewGitLab(testConfig(), idp.URL+"/"), ctx
+	case "google":
+		target, _ := url.Parse(idp.URL)
+		client := &http.Client{Transport: rewriteTransport{target: target}}
+		return NewGoogle(testConfig()), context.WithValue(ctx, oauth2.HTTPClient, client)
+	case "oidc":
+		p, err := NewOIDC(ctx, "company", testConfig(), idp.URL+"/")
+		if err != nil {
+			t.Fatalf("NewOIDC: %v", err)
+		}
+		return p, ctx
+	}
+	t.Fatalf("unknown provider %s", name)
+	return nil, nil
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	verifier := oauth2.GenerateVerifier()
+	tests := []struct {
+		provider string
+		authURL  string // 空字符串表示测试服务器地址
+		path     string
+		scope    string
+	}{
+		{"github", "", "/login/oauth/authorize", "read:user user:email"},
+		{"gitlab", "", "/oauth/authorize", "read_user"},
+		{"google", "https://accounts.google.com", "/o/oauth2/auth", "openid email profile"},
+		{"oidc", "", "/authorize", "openid email profile"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			idp := newFakeIdP(t)
+			p, _ := newTestProvider(t, idp, tt.provider)
+
+			raw := p.AuthCodeURL("state-1", oauth2.AccessTypeOffline, oauth2.S256ChallengeOption(verifier))
+			u, err := url.Parse(raw)
+			if err != nil {
+				t.Fatalf("invalid auth URL %q: %v", raw, err)
+			}
+			base := tt.authURL
+			if base == "" {
+				base = idp.URL
+			}
+			if got := u.Scheme + "://" + u.Host + u.Path; got != base+tt.path {
+				t.Errorf("auth URL = %s, want %s", got, base+tt.path)
+			}
+
+			want := map[string]string{
+				"client_id":             testClientID,
+				"redirect_uri":          testRedirectURL,
+				"response_type":         "code",
+				"state":                 "state-1",
+				"scope":                 tt.scope,
+				"access_type":           "offline",
+				"code_challenge":        oauth2.S256ChallengeFromVerifier(verifier),
+				"code_challenge_method": "S256",
+			}
+			query := u.Query()
+			for key, value := range want {
+				if got := query.Get(key); got != value {
+					t.Errorf("%s = %q, want %q", key, got, value)
+				}
+			}
+		})
+	}
+}
+
+func TestExchangeAndFetchProfile(t *testing.T) {
+	tests := []struct {
+		provider string
+		path     string
+		body     string
+		want     Profile
+	}{
+		{
+			provider: "github",
+			path:     "/user",
+			body:     `{"id": 1001, "login": "octocat", "avatar_url": "https://avatars.test/octocat", "email": "octocat@example.com"}`,
+			want:     Profile{Subject: "1001", Username: "octocat", Email: "octocat@example.com", AvatarURL: "https://avatars.test/octocat"},
+		},
+		{
+			provider: "gitlab",
+			path:     "/api/v4/user",
+			body:     `{"id": 2002, "username": "tanuki", "email": "tanuki@example.com", "avatar_url": "https://avatars.test/tanuki"}`,
+			want:     Profile{Subject: "2002", Username: "tanuki", Email: "tanuki@example.com", AvatarURL: "https://avatars.test/tanuki"},
+		},
+		{
+			provider: "google",
+			path:     "/v1/userinfo",
+			body:     `{"sub": "g-3003", "name": "Jane Doe", "email": "jane@example.com", "picture": "https://avatars.test/jane"}`,
+			want:     Profile{Subject: "g-3003", Username: "Jane Doe", Email: "jane@example.com", AvatarURL: "https://avatars.test/jane"},
+		},
+		{
+			provider: "oidc",
+			path:     "/userinfo",
+			body:     `{"sub": "o-4004", "preferred_username": "jdoe", "name": "Jane Doe", "email": "jdoe@example.com"}`,
+			want:     Profile{Subject: "o-4004", Username: "jdoe", Email: "jdoe@example.com"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			idp := newFakeIdP(t)
+			idp.verifier = oauth2.GenerateVerifier()
+			idp.profiles[tt.path] = tt.body
+			p, ctx := newTestProvider(t, idp, tt.provider)
+
+			token, err := p.Exchange(ctx, testAuthCode, oauth2.VerifierOption(idp.verifier))
+			if err != nil {
+				t.Fatalf("Exchange: %v", err)
+			}
+			if token.AccessToken != testAccessToken {
+				t.Fatalf("access token = %q, want %q", token.AccessToken, testAccessToken)
+			}
+
+			profile, err := p.FetchProfile(ctx, token)
+			if err != nil {
+				t.Fatalf("FetchProfile: %v", err)
+			}
+			if !reflect.DeepEqual(*profile, tt.want) {
+				t.Errorf("profile = %+v, want %+v", *profile, tt.want)
+			}
+		})
+	}
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	idp := newFakeIdP(t)
+	idp.verifier = oauth2.GenerateVerifier()
+	p, ctx := newTestProvider(t, idp, "github")
+
+	if _, err := p.Exchange(ctx, testAuthCode, oauth2.VerifierOption(oauth2.GenerateVerifier())); err == nil {
+		t.Fatal("Exchange with a mismatched verifier succeeded")
+	}
+	if _, err := p.Exchange(ctx, "other-code", oauth2.VerifierOption(idp.verifier)); err == nil {
+		t.Fatal("Exchange with an unknown code succeeded")
+	}
+}
+
+func TestFetchProfile(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		path     string
+		body     string
+		want     *Profile // nil 表示应当返回错误
+	}{
+		{
+			name:     "github without id",
+			provider: "github",
+			path:     "/user",
+			body:     `{"login": "ghost"}`,
+		},
+		{
+			name:     "oidc without sub",
+			provider: "oidc",
+			path:     "/userinfo",
+			body:     `{"preferred_username": "nobody"}`,
+		},
+		{
+			name:     "malformed json",
+			provider: "gitlab",
+			path:     "/api/v4/user",
+			body:     `{"id": `,
+		},
+		{
+			name:     "username falls back to email",
+			provider: "oidc",
+			path:     "/userinfo",
+			body:     `{"sub": "o-5005", "email": "someone@example.com"}`,
+			want:     &Profile{Subject: "o-5005", Username: "someone", Email: "someone@example.com"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newFakeIdP(t)
+			idp.profiles[tt.path] = tt.body
+			p, ctx := newTestProvider(t, idp, tt.provider)
+
+			profile, err := p.FetchProfile(ctx, &oauth2.Token{AccessToken: testAccessToken, TokenType: "Bearer"})
+			if tt.want == nil {
+				if err == nil {
+					t.Fatalf("FetchProfile returned %+v, want an error", profile)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FetchProfile: %v", err)
+			}
+			if !reflect.DeepEqual(profile, tt.want) {
+				t.Errorf("profile = %+v, want %+v", profile, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchProfileRejectsUnauthorized(t *testing.T) {
+	idp := newFakeIdP(t)
+	idp.profiles["/user"] = `{"id": 1}`
+	p, ctx := newTestProvider(t, idp, "github")
+
+	_, err := p.FetchProfile(ctx, &oauth2.Token{AccessToken: "wrong-token", TokenType: "Bearer"})
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("FetchProfile error = %v, want a 401 error", err)
+	}
+}
+
+func TestNewOIDCRequiresEndpoints(t *testing.T) {
+	idp := newFakeIdP(t)
+	idp.discovery = map[string]string{
+		"authorization_endpoint": idp.URL + "/authorize",
+		"token_endpoint":         idp.URL + "/token",
+	}
+	if _, err := NewOIDC(context.Background(), "company", testConfig(), idp.URL); err == nil {
+		t.Fatal("NewOIDC succeeded without a userinfo endpoint")
+	}
+	if _, err := NewOIDC(context.Background(), "company", testConfig(), ""); err == nil {
+		t.Fatal("NewOIDC succeeded without an issuer URL")
+	}
+}
@@ -1,29 +1,74 @@
 package routes
 
 import (
+	"time"
+
+	"gin-blog/backend/config"
 	"gin-blog/backend/controllers"
 	"gin-blog/backend/middlewares"
+	"gin-blog/backend/models"
+	"gin-blog/backend/storage"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRouter(r *gin.Engine) {
+func SetupRouter(r *gin.Engine, cfg *config.Config) {
+	r.GET("/feed.xml", controllers.GetRSSFeed)
+	r.GET("/atom.xml", controllers.GetAtomFeed)
+	r.GET("/tags/:tagName/feed.xml", controllers.GetTagRSSFeed)
+	r.GET("/tags/:tagName/atom.xml", controllers.GetTagAtomFeed)
+	r.GET("/categories/:categoryId/feed.xml", controllers.GetCategoryRSSFeed)
+	r.GET("/categories/:categoryId/atom.xml", controllers.GetCategoryAtomFeed)
+
+	// 本地存储的上传文件直接以静态文件提供
+	if local, ok := storage.Default.(*storage.LocalStorage); ok {
+		r.Static(local.URLPrefix, local.Root)
+	}
+
 	api := r.Group("/api")
 
+	// 公开写接口的限流，可通过 RATE_LIMIT_LIKE、RATE_LIMIT_COMMENT 覆盖，例如 "30/1m,burst=60"
+	likeRateLimit := middlewares.RateLimit(cfg.RateLimit, "like", middlewares.Rate{Limit: 30, Per: time.Minute})
+	commentRateLimit := middlewares.RateLimit(cfg.RateLimit, "comment", middlewares.Rate{Limit: 5, Per: time.Minute})
+
 	authRoutes := api.Group("/auth")
 	{
 		authRoutes.POST("/login", controllers.Login)
-		authRoutes.GET("/github/login", controllers.HandleGitHubLogin)
-		authRoutes.GET("/github/callback", controllers.HandleGitHubCallback)
+		authRoutes.POST("/login/2fa", controllers.VerifyTwoFactorLogin)
+		authRoutes.POST("/accept-invite", controllers.AcceptInvite)
+		authRoutes.POST("/refresh", controllers.RefreshSession)
+		// 访问令牌可能已过期，注销接口不强制要求认证
+		authRoutes.POST("/logout", middlewares.OptionalAuthMiddleware(), controllers.Logout)
+		authRoutes.POST("/logout-all", middlewares.AuthMiddleware(true), controllers.LogoutAllSessions)
+
+		// 第三方登录：/api/auth/github/login、/api/auth/google/login 等
+		authRoutes.GET("/providers", controllers.GetOAuthProviders)
+		authRoutes.GET("/:provider/login", controllers.HandleOAuthLogin)
+		authRoutes.GET("/:provider/callback", controllers.HandleOAuthCallback)
+		// 关联登录方式的授权码需要携带发起关联的访客的 token 兑换
+		authRoutes.POST("/oauth/exchange", middlewares.OptionalAuthMiddleware(), controllers.ExchangeOAuthCode)
+		authRoutes.POST("/:provider/link", middlewares.AuthMiddleware(true), controllers.CreateOAuthLinkTicket)
+		authRoutes.GET("/identities", middlewares.AuthMiddleware(true), controllers.GetGuestIdentities)
+		authRoutes.DELETE("/identities/:id", middlewares.AuthMiddleware(true), controllers.UnlinkGuestIdentity)
+
+		twoFactorRoutes := authRoutes.Group("/2fa", middlewares.AuthMiddleware(false))
+		{
+			twoFactorRoutes.GET("", controllers.GetTwoFactorStatus)
+			twoFactorRoutes.POST("/setup", controllers.SetupTwoFactor)
+			twoFactorRoutes.POST("/enable", controllers.EnableTwoFactor)
+			twoFactorRoutes.POST("/disable", controllers.DisableTwoFactor)
+			twoFactorRoutes.POST("/recovery-codes", controllers.RegenerateRecoveryCodes)
+		}
 	}
 
-	postRoutes := api.Group("/posts")
+	// 公开的文章接口可选携带 token，管理员可以据此看到草稿、定时和归档文章
+	postRoutes := api.Group("/posts", middlewares.OptionalAuthMiddleware())
 	{
 		postRoutes.GET("", controllers.GetPosts)
 		postRoutes.GET("/tag/:tagName", controllers.GetPostsByTag)
 		postRoutes.GET("/:id", controllers.GetPost)
-		postRoutes.POST("/:id/like", controllers.LikePost)
-		postRoutes.POST("/:id/unlike", controllers.UnlikePost)
+		postRoutes.POST("/:id/like", likeRateLimit, controllers.LikePost)
+		postRoutes.POST("/:id/unlike", likeRateLimit, controllers.UnlikePost)
 
 		adminPostRoutes := postRoutes.Group("")
 		adminPostRoutes.Use(middlewares.AuthMiddleware(false))
@@ -31,27 +76,80 @@ func SetupRouter(r *gin.Engine) {
 			adminPostRoutes.POST("", controllers.CreatePost)
 			adminPostRoutes.PUT("/:id", controllers.UpdatePost)
 			adminPostRoutes.DELETE("/:id", controllers.DeletePost)
+
+			adminPostRoutes.GET("/:id/revisions", controllers.GetPostRevisions)
+			adminPostRoutes.GET("/:id/revisions/diff", controllers.DiffPostRevisions)
+			adminPostRoutes.GET("/:id/revisions/:revisionId", controllers.GetPostRevision)
+			adminPostRoutes.POST("/:id/revisions/:revisionId/restore", controllers.RestorePostRevision)
 		}
 		
 		
 		commentRoutes := postRoutes.Group("/:id/comments")
 		commentRoutes.Use(middlewares.AuthMiddleware(true))
 		{
-			commentRoutes.POST("", controllers.CreateComment)
+			commentRoutes.POST("", commentRateLimit, controllers.CreateComment)
+			commentRoutes.PUT("/:commentId", controllers.UpdateComment)
+			commentRoutes.DELETE("/:commentId", controllers.DeleteComment)
 		}
 		// Public route to get comments for a post
 		postRoutes.GET("/:id/comments", controllers.GetCommentsForPost)
 	}
 
-	categoryRoutes := api.Group("/categories")
+	categoryRoutes := api.Group("/categories", middlewares.OptionalAuthMiddleware())
 	{
 		categoryRoutes.GET("", controllers.GetCategories)
+		categoryRoutes.GET("/:id", controllers.GetCategory)
 		
 		protectedCategoryRoutes := categoryRoutes.Group("")
-		protectedCategoryRoutes.Use(middlewares.AuthMiddleware(false))
+		protectedCategoryRoutes.Use(middlewares.AuthMiddleware(false), middlewares.RequirePermission(models.PermManageTaxonomy))
 		{
 			protectedCategoryRoutes.POST("", controllers.CreateCategory)
+			protectedCategoryRoutes.PUT("/:id", controllers.UpdateCategory)
+			protectedCategoryRoutes.DELETE("/:id", controllers.DeleteCategory)
+		}
+	}
+
+	tagRoutes := api.Group("/tags", middlewares.OptionalAuthMiddleware())
+	{
+		tagRoutes.GET("", controllers.GetTags)
+
+		adminTagRoutes := tagRoutes.Group("")
+		adminTagRoutes.Use(middlewares.AuthMiddleware(false), middlewares.RequirePermission(models.PermManageTaxonomy))
+		{
+			adminTagRoutes.PUT("/:id", controllers.RenameTag)
+			adminTagRoutes.DELETE("/:id", controllers.DeleteTag)
+			adminTagRoutes.POST("/merge", controllers.MergeTags)
+			adminTagRoutes.POST("/cleanup", controllers.CleanupTags)
+		}
+	}
+
+	api.GET("/search", middlewares.OptionalAuthMiddleware(), controllers.Search)
+
+	uploadRoutes := api.Group("/uploads")
+	uploadRoutes.Use(middlewares.AuthMiddleware(false), middlewares.RequirePermission(models.PermUploadMedia))
+	{
+		uploadRoutes.POST("", controllers.UploadFile)
+		uploadRoutes.GET("", controllers.GetMediaList)
+		uploadRoutes.DELETE("/:id", middlewares.RequirePermission(models.PermManageMedia), controllers.DeleteMedia)
+	}
+
+	adminRoutes := api.Group("/admin")
+	adminRoutes.Use(middlewares.AuthMiddleware(false))
+	{
+		adminRoutes.GET("/comments", middlewares.RequirePermission(models.PermModerateComments), controllers.GetModerationComments)
+		adminRoutes.POST("/comments/moderate", middlewares.RequirePermission(models.PermModerateComments), controllers.ModerateComments)
+
+		userRoutes := adminRoutes.Group("/users", middlewares.RequirePermission(models.PermManageUsers))
+		{
+			userRoutes.GET("", controllers.GetUsers)
+			userRoutes.POST("/invite", controllers.InviteUser)
+			userRoutes.PUT("/:id/role", controllers.ChangeUserRole)
+			userRoutes.PUT("/:id/status", controllers.SetUserStatus)
+			userRoutes.POST("/:id/logout-all", controllers.LogoutUserSessions)
+			userRoutes.DELETE("/:id/2fa", controllers.ResetUserTwoFactor)
+			userRoutes.POST("/:id/unlock", controllers.UnlockUser)
 		}
+		adminRoutes.GET("/security-events", middlewares.RequirePermission(models.PermManageUsers), controllers.GetAuthEvents)
 	}
 
 	statsRoutes := api.Group("/stats")
@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+
+	"gin-blog/backend/config"
+)
+
+// Storage 上传文件的存储后端。目前提供本地文件系统实现，
+// 之后可以按同样的接口接入 S3 兼容的对象存储（如 MinIO）。
+type Storage interface {
+	// Save 将内容写入 key 对应的位置，已存在时覆盖
+	Save(ctx context.Context, key string, r io.Reader, contentType string) error
+	// Delete 删除 key 对应的文件，文件不存在时不报错
+	Delete(ctx context.Context, key string) error
+	// Exists 判断 key 对应的文件是否已存在
+	Exists(ctx context.Context, key string) (bool, error)
+	// URL 返回 key 对应文件的公开访问地址
+	URL(key string) string
+}
+
+// Default 当前使用的存储后端
+var Default Storage
+
+// SetupStorage 根据配置的存储驱动初始化存储后端
+func SetupStorage(cfg config.StorageConfig) {
+	switch cfg.Driver {
+	case "", "local":
+		local, err := NewLocalStorage(cfg.Dir, cfg.URLPrefix)
+		if err != nil {
+			log.Fatalf("Failed to initialize local storage: %v", err)
+		}
+		Default = local
+		log.Printf("Using local storage at %s, served under %s.", local.Root, local.URLPrefix)
+	default:
+		log.Fatal(fmt.Sprintf("Unsupported STORAGE_DRIVER %q", cfg.Driver))
+	}
+}
@@ -0,0 +1,166 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+type diffOp struct {
+	kind byte // ' ' 未变化, '-' 删除, '+' 新增
+	line string
+}
+
+// maxLCSCells 限制最长公共子序列表的大小（约 16MB），超出时退化为整段替换，
+// 避免两个很长的版本比较时占用过多内存和 CPU
+const maxLCSCells = 4 << 20
+
+// diffLines 计算两段文本的逐行差异：先去掉相同的首尾行，中间部分基于最长公共子序列比较
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	ops = append(ops, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
+
+func diffMiddle(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	ops := make([]diffOp, 0, n+m)
+	if (n+1)*(m+1) > maxLCSCells {
+		for _, line := range a {
+			ops = append(ops, diffOp{'-', line})
+		}
+		for _, line := range b {
+			ops = append(ops, diffOp{'+', line})
+		}
+		return ops
+	}
+
+	// lcs[i*(m+1)+j] 为 a[i:] 与 b[j:] 的最长公共子序列长度
+	width := m + 1
+	lcs := make([]int32, (n+1)*width)
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i*width+j] = lcs[(i+1)*width+j+1] + 1
+			} else if lcs[(i+1)*width+j] >= lcs[i*width+j+1] {
+				lcs[i*width+j] = lcs[(i+1)*width+j]
+			} else {
+				lcs[i*width+j] = lcs[i*width+j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[(i+1)*width+j] >= lcs[i*width+j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// hunkRange 按 unified diff 格式输出行号范围
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// UnifiedDiff 生成两段文本之间的 unified diff，context 为每个变更块保留的上下文行数。
+// 两段文本相同时返回空字符串。
+func UnifiedDiff(fromName, toName, a, b string, context int) string {
+	ops := diffLines(splitLines(a), splitLines(b))
+
+	var changes []int
+	for idx, op := range ops {
+		if op.kind != ' ' {
+			changes = append(changes, idx)
+		}
+	}
+	if len(changes) == 0 {
+		return ""
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+
+	// 记录每个操作之前 a、b 中已经出现的行数，用于计算变更块的起始行号
+	aPos := make([]int, len(ops)+1)
+	bPos := make([]int, len(ops)+1)
+	for idx, op := range ops {
+		aPos[idx+1], bPos[idx+1] = aPos[idx], bPos[idx]
+		if op.kind != '+' {
+			aPos[idx+1]++
+		}
+		if op.kind != '-' {
+			bPos[idx+1]++
+		}
+	}
+
+	for k := 0; k < len(changes); {
+		start := changes[k] - context
+		if start < 0 {
+			start = 0
+		}
+		end := changes[k] + context + 1
+		// 相邻变更的上下文重叠时合并为同一个变更块
+		for k+1 < len(changes) && changes[k+1]-context <= end {
+			k++
+			end = changes[k] + context + 1
+		}
+		if end > len(ops) {
+			end = len(ops)
+		}
+		k++
+
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n",
+			hunkRange(aPos[start], aPos[end]-aPos[start]),
+			hunkRange(bPos[start], bPos[end]-bPos[start]))
+		for _, op := range ops[start:end] {
+			out.WriteByte(op.kind)
+			out.WriteString(op.line)
+			out.WriteByte('\n')
+		}
+	}
+	return out.String()
+}
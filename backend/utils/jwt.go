@@ -1,14 +1,16 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtKey = []byte(os.Getenv("JWT_SECRET"))
+// jwtKey 签名密钥，启动时由 ConfigureJWT 设置
+var jwtKey []byte
 
 type Claims struct {
 	UserID      uint   `json:"user_id,omitempty"`
@@ -16,34 +18,56 @@ type Claims struct {
 	Username    string `json:"username"`
 	AvatarURL	string `json:"avatar_url,omitempty"`
 	Provider    string `json:"provider,omitempty"`
+	// TokenVersion 与用户记录中的 token_version 不一致时 token 失效，用于"退出所有会话"
+	TokenVersion int `json:"tv"`
 	jwt.RegisteredClaims
 }
 
-func ensureJwtKey() {
-	if string(jwtKey) == "" {
-		loadedKey := os.Getenv("JWT_SECRET")
-		if loadedKey == "" || loadedKey == "your_super_secret_key_change_this" {
-			fmt.Println("Warning: JWT_SECRET is not set or is using the default insecure value. Please set it in .env")
-			if loadedKey == "" {
-				jwtKey = []byte("fallback_secret_key_for_dev_only_change_me")
-			} else {
-				jwtKey = []byte(loadedKey)
-			}
-		} else {
-			jwtKey = []byte(loadedKey)
-		}
+// accessTokenTTL 访问令牌有效期，启动时由 ConfigureJWT 设置
+var accessTokenTTL = 15 * time.Minute
+
+// TokenRevocationCheck 由上层注入，用于检查 token 是否已被吊销（jti 黑名单、token 版本）。
+// utils 不直接访问数据库，未注入时不做吊销检查。
+var TokenRevocationCheck func(claims *Claims) error
+
+// ConfigureJWT 设置签名密钥和访问令牌有效期，必须在签发或校验 token 之前调用
+func ConfigureJWT(secret string, accessTTL time.Duration) {
+	jwtKey = []byte(secret)
+	if accessTTL > 0 {
+		accessTokenTTL = accessTTL
 	}
 }
 
-func GenerateToken(id uint, username string, avatarURL string, userType string) (string, error) {
-	ensureJwtKey()
-	expirationTime := time.Now().Add(7 * 24 * time.Hour) // Extended to 7 days for guest convenience
-	
+// AccessTokenTTL 返回访问令牌的有效期
+func AccessTokenTTL() time.Duration {
+	return accessTokenTTL
+}
+
+func newTokenID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// GenerateToken 签发短期访问令牌，返回 token 及其过期时间；长期登录依靠刷新令牌续期。
+// provider 为访客登录时使用的第三方登录提供方，后台用户固定为 local。
+func GenerateToken(id uint, username string, avatarURL string, userType string, provider string, tokenVersion int) (string, time.Time, error) {
+	expirationTime := time.Now().Add(AccessTokenTTL())
+
+	jti, err := newTokenID()
+	if err != nil {
+		return "", time.Time{}, err
+	}
 	claims := &Claims{
-		Username:  username,
-		AvatarURL: avatarURL,
+		Username:     username,
+		AvatarURL:    avatarURL,
+		TokenVersion: tokenVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Issuer:    "gin-blog",
 		},
 	}
@@ -53,17 +77,17 @@ func GenerateToken(id uint, username string, avatarURL string, userType string)
 		claims.Provider = "local"
 	} else if userType == "guest" {
 		claims.GuestUserID = id
-		claims.Provider = "github"
+		claims.Provider = provider
 	} else {
-		return "", fmt.Errorf("unknown user type for token generation")
+		return "", time.Time{}, fmt.Errorf("unknown user type for token generation")
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtKey)
+	signed, err := token.SignedString(jwtKey)
+	return signed, expirationTime, err
 }
 
 func ValidateToken(tokenStr string) (*Claims, error) {
-	ensureJwtKey()
 	claims := &Claims{}
 	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
 		return jwtKey, nil
@@ -80,5 +104,19 @@ func ValidateToken(tokenStr string) (*Claims, error) {
 		return nil, fmt.Errorf("invalid token")
 	}
 
+	// 带 audience 的是 OAuth state、两步验证凭证等专用 token，不能当作访问令牌使用
+	if len(claims.Audience) > 0 {
+		return nil, fmt.Errorf("invalid token")
+	}
+	// 旧版本签发的 token 没有 jti，无法吊销，要求重新登录
+	if claims.ID == "" {
+		return nil, fmt.Errorf("token is outdated, please log in again")
+	}
+	if TokenRevocationCheck != nil {
+		if err := TokenRevocationCheck(claims); err != nil {
+			return nil, err
+		}
+	}
+
 	return claims, nil
 }
\ No newline at end of file
@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const loginChallengeAudience = "2fa-challenge"
+
+// LoginChallenge 密码验证通过、等待两步验证时签发的短期凭证，不能用于访问任何接口
+type LoginChallenge struct {
+	UserID       uint `json:"user_id"`
+	TokenVersion int  `json:"tv"`
+	jwt.RegisteredClaims
+}
+
+// SignLoginChallenge 签发两步验证凭证，返回凭证及其过期时间
+func SignLoginChallenge(challenge *LoginChallenge, ttl time.Duration) (string, time.Time, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	challenge.RegisteredClaims = registeredClaimsFor(loginChallengeAudience, ttl)
+	challenge.ID = jti
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, challenge).SignedString(jwtKey)
+	return signed, challenge.ExpiresAt.Time, err
+}
+
+// ParseLoginChallenge 校验并取出两步验证凭证
+func ParseLoginChallenge(tokenStr string) (*LoginChallenge, error) {
+	challenge := &LoginChallenge{}
+	if err := parseWithAudience(tokenStr, challenge, loginChallengeAudience); err != nil {
+		return nil, fmt.Errorf("invalid login challenge: %v", err)
+	}
+	return challenge, nil
+}
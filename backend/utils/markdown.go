@@ -0,0 +1,205 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// wordsPerMinute 估算阅读时长时使用的阅读速度（中文按字、英文按词计）
+const wordsPerMinute = 300
+
+// markdown 使用 GFM 与脚注扩展；默认不输出原始 HTML，渲染结果还会再经过 sanitizer 过滤。
+// 代码块会带上 language-xxx 样式类，供前端的 highlight.js / Prism 等高亮。
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM, extension.Footnote),
+	goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+)
+
+var sanitizer = newSanitizer()
+
+func newSanitizer() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("id").OnElements("h1", "h2", "h3", "h4", "h5", "h6", "li", "sup")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#.-]+$`)).OnElements("code")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^footnotes?(-ref|-backref)?$`)).OnElements("a", "div")
+	p.AllowAttrs("role").Matching(regexp.MustCompile(`^doc-(noteref|endnotes|backlink)$`)).OnElements("a", "div")
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	return p
+}
+
+// Heading 文档中的一个标题，用于生成目录
+type Heading struct {
+	Level int
+	ID    string
+	Text  string
+}
+
+// RenderedMarkdown Markdown 的渲染结果
+type RenderedMarkdown struct {
+	HTML      string
+	Headings  []Heading
+	WordCount int
+}
+
+// ReadingTimeMinutes 按字数估算阅读时长，非空文章至少为 1 分钟
+func (r *RenderedMarkdown) ReadingTimeMinutes() int {
+	if r.WordCount == 0 {
+		return 0
+	}
+	return int(math.Ceil(float64(r.WordCount) / wordsPerMinute))
+}
+
+// RenderMarkdown 将 Markdown 文本渲染为经过过滤的 HTML
+func RenderMarkdown(source string) (string, error) {
+	rendered, err := RenderMarkdownDocument(source)
+	if err != nil {
+		return "", err
+	}
+	return rendered.HTML, nil
+}
+
+// RenderMarkdownDocument 渲染 Markdown，并提取标题目录和字数
+func RenderMarkdownDocument(source string) (*RenderedMarkdown, error) {
+	src := []byte(source)
+	ctx := parser.NewContext(parser.WithIDs(newHeadingIDs()))
+	doc := markdown.Parser().Parse(text.NewReader(src), parser.WithContext(ctx))
+
+	var buf bytes.Buffer
+	if err := markdown.Renderer().Render(&buf, src, doc); err != nil {
+		return nil, err
+	}
+
+	result := &RenderedMarkdown{HTML: sanitizer.Sanitize(buf.String())}
+	var plain strings.Builder
+	err := ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := n.(type) {
+		case *ast.Heading:
+			heading := Heading{Level: node.Level, Text: strings.TrimSpace(string(nodeText(node, src)))}
+			if id, ok := node.AttributeString("id"); ok {
+				if b, ok := id.([]byte); ok {
+					heading.ID = string(b)
+				}
+			}
+			result.Headings = append(result.Headings, heading)
+		case *ast.Text:
+			plain.Write(node.Segment.Value(src))
+			plain.WriteByte(' ')
+		case *ast.FencedCodeBlock, *ast.CodeBlock:
+			lines := n.Lines()
+			for i := 0; i < lines.Len(); i++ {
+				segment := lines.At(i)
+				plain.Write(segment.Value(src))
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.WordCount = CountWords(plain.String())
+	return result, nil
+}
+
+// nodeText 拼接节点下所有文本内容
+func nodeText(n ast.Node, src []byte) []byte {
+	var buf bytes.Buffer
+	for child := n.FirstChild(); child != nil; child = child.NextSibling() {
+		switch node := child.(type) {
+		case *ast.Text:
+			buf.Write(node.Segment.Value(src))
+		case *ast.String:
+			buf.Write(node.Value)
+		default:
+			buf.Write(nodeText(child, src))
+		}
+	}
+	return buf.Bytes()
+}
+
+// CountWords 统计字数：中日韩文字每个字计一个，其他语言按连续的字母数字计一个词
+func CountWords(s string) int {
+	count := 0
+	inWord := false
+	for _, r := range s {
+		switch {
+		case isCJK(r):
+			count++
+			inWord = false
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || (inWord && (r == '\'' || r == '-' || r == '_')):
+			if !inWord {
+				count++
+				inWord = true
+			}
+		default:
+			inWord = false
+		}
+	}
+	return count
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
+
+// headingIDs 生成标题锚点，与 goldmark 默认实现不同的是会保留中文等非 ASCII 字符
+type headingIDs struct {
+	values map[string]bool
+}
+
+func newHeadingIDs() *headingIDs {
+	return &headingIDs{values: map[string]bool{}}
+}
+
+func (s *headingIDs) Generate(value []byte, kind ast.NodeKind) []byte {
+	var b strings.Builder
+	lastDash := false
+	for _, r := range strings.TrimSpace(string(value)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(unicode.ToLower(r))
+			lastDash = false
+		case (unicode.IsSpace(r) || r == '-' || r == '_') && !lastDash && b.Len() > 0:
+			b.WriteByte('-')
+			lastDash = true
+		}
+	}
+	id := strings.TrimSuffix(b.String(), "-")
+	if id == "" {
+		if kind == ast.KindHeading {
+			id = "heading"
+		} else {
+			id = "id"
+		}
+	}
+	if !s.values[id] {
+		s.values[id] = true
+		return []byte(id)
+	}
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s-%d", id, i)
+		if !s.values[candidate] {
+			s.values[candidate] = true
+			return []byte(candidate)
+		}
+	}
+}
+
+func (s *headingIDs) Put(value []byte) {
+	s.values[string(value)] = true
+}
@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/mozillazg/go-pinyin"
+	"golang.org/x/text/unicode/norm"
+)
+
+// maxSlugLength 生成的 slug 最大长度（字节），超出部分在单词边界处截断
+const maxSlugLength = 80
+
+var pinyinArgs = pinyin.NewArgs()
+
+// Slugify 将标题或名称转换为 URL 友好的 slug：
+// 拉丁字母转小写并去掉重音符号，汉字转换为不带声调的拼音，其余字符作为分隔符。
+func Slugify(s string) string {
+	var words []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			words = append(words, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, r := range norm.NFKD.String(s) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// 去掉分解后的重音符号，如 é -> e
+		case unicode.Is(unicode.Han, r):
+			flush()
+			if py := pinyin.LazyPinyin(string(r), pinyinArgs); len(py) > 0 {
+				words = append(words, py[0])
+			}
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			current.WriteRune(unicode.ToLower(r))
+		default:
+			flush()
+		}
+	}
+	flush()
+
+	var slug strings.Builder
+	for _, word := range words {
+		if slug.Len() > 0 && slug.Len()+1+len(word) > maxSlugLength {
+			break
+		}
+		if slug.Len() > 0 {
+			slug.WriteByte('-')
+		}
+		if len(word) > maxSlugLength {
+			word = word[:maxSlugLength]
+		}
+		slug.WriteString(word)
+	}
+	return slug.String()
+}
@@ -22,15 +22,41 @@ apiClient.interceptors.request.use(
     }
 );
 
+// 同一时间只发起一次刷新，其余 401 请求等待同一个结果
+let refreshPromise = null;
+
+const refreshAccessToken = (authStore) => {
+    if (!refreshPromise) {
+        refreshPromise = axios
+            .post(`${import.meta.env.VITE_API_BASE_URL}/auth/refresh`, { refresh_token: authStore.refreshToken })
+            .then((response) => {
+                authStore.setTokens(response.data.token, response.data.refresh_token);
+                return response.data.token;
+            })
+            .finally(() => {
+                refreshPromise = null;
+            });
+    }
+    return refreshPromise;
+};
+
 apiClient.interceptors.response.use(
     response => response,
-    error => {
-        if (error.response && error.response.status === 401) {
+    async error => {
+        const original = error.config;
+        if (error.response && error.response.status === 401 && original && !original._retried) {
             const authStore = useAuthStore();
-            // Only logout if it's not a guest session being established or if token is truly invalid
-            // This might need more nuanced handling depending on the error source
-            if (authStore.isAuthenticated) { // Avoid logging out if it's a guest just failing an op
-                 // authStore.logout(); // Potentially too aggressive
+            // 访问令牌有效期很短，过期后用刷新令牌换取新令牌并重试一次
+            if (authStore.refreshToken && !original.url.startsWith('/auth/')) {
+                original._retried = true;
+                try {
+                    const token = await refreshAccessToken(authStore);
+                    original.headers.Authorization = `Bearer ${token}`;
+                    return apiClient(original);
+                } catch (refreshError) {
+                    // 刷新令牌已失效或被吊销，只清除本地登录状态
+                    authStore.clearSession();
+                }
             }
         }
         return Promise.reject(error);
@@ -38,9 +64,18 @@ apiClient.interceptors.response.use(
 );
 
 export const loginUser = (credentials) => apiClient.post('/auth/login', credentials);
+export const verifyTwoFactorLogin = (challengeToken, { code, recoveryCode }) => apiClient.post('/auth/login/2fa', { challenge_token: challengeToken, code, recovery_code: recoveryCode });
+export const fetchTwoFactorStatus = () => apiClient.get('/auth/2fa');
+export const setupTwoFactor = () => apiClient.post('/auth/2fa/setup');
+export const enableTwoFactor = (code) => apiClient.post('/auth/2fa/enable', { code });
+export const disableTwoFactor = (password, { code, recoveryCode }) => apiClient.post('/auth/2fa/disable', { password, code, recovery_code: recoveryCode });
+export const regenerateRecoveryCodes = (password, { code, recoveryCode }) => apiClient.post('/auth/2fa/recovery-codes', { password, code, recovery_code: recoveryCode });
+// 退出时本地状态会立即清空，因此显式带上待注销的访问令牌
+export const logoutSession = (token, refreshToken) => apiClient.post('/auth/logout', { refresh_token: refreshToken }, { headers: { Authorization: `Bearer ${token}` } });
+export const logoutAllSessions = () => apiClient.post('/auth/logout-all');
 
-export const fetchPosts = () => apiClient.get('/posts');
-export const fetchPostsByTag = (tagName) => apiClient.get(`/posts/tag/${tagName}`);
+export const fetchPosts = (params = {}) => apiClient.get('/posts', { params });
+export const fetchPostsByTag = (tagName, params = {}) => apiClient.get(`/posts/tag/${encodeURIComponent(tagName)}`, { params });
 export const fetchPostById = (id) => apiClient.get(`/posts/${id}`);
 export const createPost = (postData) => apiClient.post('/posts', postData);
 export const updatePost = (id, postData) => apiClient.put(`/posts/${id}`, postData);
@@ -51,15 +86,58 @@ export const unlikePost = (id) => apiClient.post(`/posts/${id}/unlike`);
 
 export const fetchCategories = () => apiClient.get('/categories');
 export const createCategory = (categoryData) => apiClient.post('/categories', categoryData);
+export const updateCategory = (id, categoryData) => apiClient.put(`/categories/${id}`, categoryData);
+export const deleteCategory = (id, reassignTo) => apiClient.delete(`/categories/${id}`, { params: reassignTo ? { reassign_to: reassignTo } : {} });
+
+export const fetchTags = (params = {}) => apiClient.get('/tags', { params });
+export const renameTag = (id, name) => apiClient.put(`/tags/${id}`, { name });
+export const deleteTag = (id) => apiClient.delete(`/tags/${id}`);
+export const mergeTags = (sourceIds, targetId) => apiClient.post('/tags/merge', { source_ids: sourceIds, target_id: targetId });
+export const cleanupTags = (dryRun = false) => apiClient.post('/tags/cleanup', null, { params: dryRun ? { dry_run: true } : {} });
 
 export const fetchBlogStats = () => apiClient.get('/stats');
 
-// GitHub OAuth - This is a redirect, not an API call in the traditional sense
-export const GITHUB_LOGIN_URL = `${import.meta.env.VITE_API_BASE_URL}/auth/github/login`;
+export const searchPosts = (q, params = {}) => apiClient.get('/search', { params: { q, ...params } });
+
+// OAuth login - This is a redirect, not an API call in the traditional sense
+export const oauthLoginURL = (provider) => `${import.meta.env.VITE_API_BASE_URL}/auth/${provider}/login`;
+export const GITHUB_LOGIN_URL = oauthLoginURL('github');
+export const fetchOAuthProviders = () => apiClient.get('/auth/providers');
+export const exchangeOAuthCode = (code) => apiClient.post('/auth/oauth/exchange', { code });
+export const linkOAuthProvider = (provider, redirect) => apiClient.post(`/auth/${provider}/link`, null, { params: redirect ? { redirect } : {} });
+export const fetchGuestIdentities = () => apiClient.get('/auth/identities');
+export const unlinkGuestIdentity = (id) => apiClient.delete(`/auth/identities/${id}`);
 
 // Comments API
 export const fetchCommentsByPostId = (postId) => apiClient.get(`/posts/${postId}/comments`);
 export const createComment = (postId, commentData) => apiClient.post(`/posts/${postId}/comments`, commentData);
+export const updateComment = (postId, commentId, commentData) => apiClient.put(`/posts/${postId}/comments/${commentId}`, commentData);
+export const deleteComment = (postId, commentId) => apiClient.delete(`/posts/${postId}/comments/${commentId}`);
+
+// Comment moderation (admin)
+export const fetchModerationComments = (params = {}) => apiClient.get('/admin/comments', { params });
+export const moderateComments = (ids, status) => apiClient.post('/admin/comments/moderate', { ids, status });
+
+// User management (owner)
+export const fetchUsers = () => apiClient.get('/admin/users');
+export const inviteUser = (username, role) => apiClient.post('/admin/users/invite', { username, role });
+export const changeUserRole = (id, role) => apiClient.put(`/admin/users/${id}/role`, { role });
+export const setUserDisabled = (id, disabled) => apiClient.put(`/admin/users/${id}/status`, { disabled });
+export const logoutUserSessions = (id) => apiClient.post(`/admin/users/${id}/logout-all`);
+export const resetUserTwoFactor = (id) => apiClient.delete(`/admin/users/${id}/2fa`);
+export const unlockUser = (id) => apiClient.post(`/admin/users/${id}/unlock`);
+export const fetchSecurityEvents = (params) => apiClient.get('/admin/security-events', { params });
+export const acceptInvite = (token, password) => apiClient.post('/auth/accept-invite', { token, password });
+
+// Media library (admin)
+export const uploadFile = (file, postId) => {
+  const form = new FormData();
+  form.append('file', file);
+  if (postId) form.append('post_id', postId);
+  return apiClient.post('/uploads', form, { headers: { 'Content-Type': 'multipart/form-data' } });
+};
+export const fetchMedia = (params = {}) => apiClient.get('/uploads', { params });
+export const deleteMedia = (id) => apiClient.delete(`/uploads/${id}`);
 
 
 export default apiClient;
\ No newline at end of file
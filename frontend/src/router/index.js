@@ -95,6 +95,7 @@ router.beforeEach((to, from, next) => {
                 // This is a simplified rehydration.
                 // For guest users, setGuestSession might be more appropriate if it handles all necessary fields.
                 authStore.token = token;
+                authStore.refreshToken = localStorage.getItem('refreshToken');
                 authStore.user = user;
             }
         } catch (e) {
@@ -118,10 +119,7 @@ router.beforeEach((to, from, next) => {
         if (to.meta.requiresAdmin && !isAdmin) {
             // If requires admin but user is not admin (e.g., guest), clear session and redirect to admin login
             // Directly clear auth state to avoid circular routing from logout() method
-            authStore.token = null;
-            authStore.user = null;
-            localStorage.removeItem('token');
-            localStorage.removeItem('user');
+            authStore.clearSession();
             next({ name: 'AdminLogin', query: { redirect: to.fullPath } });
             return;
         }
@@ -1,11 +1,14 @@
 import { defineStore } from 'pinia';
-import { loginUser as apiLogin } from '../api';
+import { loginUser as apiLogin, verifyTwoFactorLogin, logoutSession, logoutAllSessions } from '../api';
 import router from '../router';
 
 export const useAuthStore = defineStore('auth', {
     state: () => ({
         token: localStorage.getItem('token') || null,
+        refreshToken: localStorage.getItem('refreshToken') || null,
         user: JSON.parse(localStorage.getItem('user')) || null, // user: { id (adminId or guestId), username, type ('admin'/'guest'), avatarUrl (for guest) }
+        // 开启两步验证的账号在密码验证通过后得到的短期凭证
+        twoFactorChallenge: null,
         error: null,
         loading: false,
     }),
@@ -21,24 +24,66 @@ export const useAuthStore = defineStore('auth', {
             this.error = null;
             try {
                 const response = await apiLogin(credentials);
-                const { token, user_id, username } = response.data;
-                this.token = token;
-                this.user = { id: user_id, username, type: 'admin' };
-                localStorage.setItem('token', token);
-                localStorage.setItem('user', JSON.stringify(this.user));
-                router.push({ name: 'AdminDashboard' });
+                if (response.data.two_factor_required) {
+                    this.twoFactorChallenge = response.data.challenge_token;
+                    return;
+                }
+                this.completeAdminLogin(response.data);
             } catch (err) {
                 this.error = err.response?.data?.error || '登录失败';
-                this.token = null;
-                this.user = null;
-                localStorage.removeItem('token');
-                localStorage.removeItem('user');
+                this.clearSession();
+            } finally {
+                this.loading = false;
+            }
+        },
+        async verifyTwoFactor(factor) { // factor: { code } 或 { recoveryCode }
+            this.loading = true;
+            this.error = null;
+            try {
+                const response = await verifyTwoFactorLogin(this.twoFactorChallenge, factor);
+                this.completeAdminLogin(response.data);
+            } catch (err) {
+                this.error = err.response?.data?.error || '验证失败';
             } finally {
                 this.loading = false;
             }
         },
+        cancelTwoFactor() {
+            this.twoFactorChallenge = null;
+            this.error = null;
+        },
+        completeAdminLogin(data) {
+            const { token, refresh_token, user_id, username } = data;
+            this.twoFactorChallenge = null;
+            this.setTokens(token, refresh_token);
+            this.user = { id: user_id, username, type: 'admin' };
+            localStorage.setItem('user', JSON.stringify(this.user));
+            router.push({ name: 'AdminDashboard' });
+        },
+        setTokens(token, refreshToken) {
+            this.token = token;
+            this.refreshToken = refreshToken || null;
+            localStorage.setItem('token', token);
+            if (refreshToken) {
+                localStorage.setItem('refreshToken', refreshToken);
+            } else {
+                localStorage.removeItem('refreshToken');
+            }
+        },
+        clearSession() {
+            this.token = null;
+            this.refreshToken = null;
+            this.user = null;
+            localStorage.removeItem('token');
+            localStorage.removeItem('refreshToken');
+            localStorage.removeItem('user');
+        },
         setGuestSession(data) {
-            this.token = data.token;
+            if (!data.token) {
+                this.error = data.error || null;
+                return;
+            }
+            this.setTokens(data.token, data.refresh_token);
             this.user = {
                 id: data.guest_id, // Store guest_id as the primary id for guests
                 guestId: data.guest_id,
@@ -46,16 +91,16 @@ export const useAuthStore = defineStore('auth', {
                 avatarUrl: data.avatar_url,
                 type: 'guest',
             };
-            localStorage.setItem('token', this.token);
             localStorage.setItem('user', JSON.stringify(this.user));
             this.error = data.error || null;
         },
         logout() {
             const userType = this.user?.type;
-            this.token = null;
-            this.user = null;
-            localStorage.removeItem('token');
-            localStorage.removeItem('user');
+            if (this.token) {
+                // 通知后端吊销令牌，失败不影响本地退出
+                logoutSession(this.token, this.refreshToken).catch(() => {});
+            }
+            this.clearSession();
             if (userType === 'admin') {
                 router.push('/admin/login');
             } else {
@@ -72,6 +117,10 @@ export const useAuthStore = defineStore('auth', {
                 }
             }
         },
+        async logoutEverywhere() {
+            await logoutAllSessions();
+            this.logout();
+        },
         clearError() {
             this.error = null;
         }